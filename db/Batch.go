@@ -3,6 +3,7 @@ package db
 import (
 	"storage-engine-workshop/db/model"
 	"storage-engine-workshop/log"
+	"storage-engine-workshop/storage/comparator"
 )
 
 type Batch struct {
@@ -18,11 +19,28 @@ func NewBatch() *Batch {
 }
 
 func (batch *Batch) add(key, value model.Slice) {
-	keyValuePair := model.KeyValuePair{Key: key, Value: value}
+	batch.append(model.KeyValuePair{Key: key, Value: value})
+}
+
+func (batch *Batch) delete(key model.Slice) {
+	batch.append(model.KeyValuePair{Key: key, Value: model.NilSlice(), Deleted: true})
+}
+
+func (batch *Batch) append(keyValuePair model.KeyValuePair) {
 	batch.keyValuePairs = append(batch.keyValuePairs, keyValuePair)
 	batch.persistentLogSlice.Add(log.NewPersistentLogSlice(keyValuePair))
 }
 
+// get returns the last write of the key in the batch
+func (batch *Batch) get(key model.Slice, keyComparator comparator.KeyComparator) (model.KeyValuePair, bool) {
+	for index := len(batch.keyValuePairs) - 1; index >= 0; index-- {
+		if keyComparator.Compare(batch.keyValuePairs[index].Key, key) == 0 {
+			return batch.keyValuePairs[index], true
+		}
+	}
+	return model.KeyValuePair{}, false
+}
+
 func (batch *Batch) allEntriesAsPersistentLogSlice() log.PersistentLogSlice {
 	return *(batch.persistentLogSlice)
 }
@@ -31,12 +49,21 @@ func (batch *Batch) isEmpty() bool {
 	return batch.totalPairs() == 0
 }
 
-func (batch *Batch) isTotalSizeGreaterThan(allowedSize uint16) bool {
+func (batch *Batch) isTotalSizeGreaterThan(allowedSize uint32) bool {
 	return batch.totalSize() > allowedSize
 }
 
-func (batch *Batch) totalSize() uint16 {
-	return uint16(batch.persistentLogSlice.Size())
+func (batch *Batch) hasValueLargerThan(sizeBytes int) bool {
+	for _, keyValuePair := range batch.keyValuePairs {
+		if keyValuePair.Value.Size() > sizeBytes {
+			return true
+		}
+	}
+	return false
+}
+
+func (batch *Batch) totalSize() uint32 {
+	return uint32(batch.persistentLogSlice.Size())
 }
 
 func (batch *Batch) totalPairs() int {
@@ -63,7 +63,7 @@ func TestTotalBatchSize(t *testing.T) {
 	slice.Add(wal.NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte("key-3")), Value: model.NewSlice([]byte("value-3"))}))
 	expectedSize := slice.Size()
 
-	if batch.totalSize() != uint16(expectedSize) {
+	if batch.totalSize() != uint32(expectedSize) {
 		t.Fatalf("Expected batch size to be %v, received %v", expectedSize, batch.totalSize())
 	}
 }
@@ -1,19 +1,75 @@
 package db
 
-import "storage-engine-workshop/storage/comparator"
+import (
+	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/sst"
+)
 
 type Configuration struct {
-	directory           string
-	segmentMaxSizeBytes uint64
-	bufferSizeBytes     uint64
-	keyComparator       comparator.KeyComparator
+	directory             string
+	segmentMaxSizeBytes   uint64
+	bufferSizeBytes       uint64
+	keyComparator         comparator.KeyComparator
+	compactionOptions     sst.CompactionOptions
+	syncPolicy            SyncPolicy
+	valueThresholdBytes   int
+	blockCacheSizeBytes   int64
+	compressionCodec      sst.CompressionCodec
+	maxImmutableMemTables int
 }
 
+const (
+	defaultBlockCacheSizeBytes   int64 = 8 * 1024 * 1024
+	defaultMaxImmutableMemTables       = 2
+)
+
 func NewConfiguration(directory string, segmentMaxSizeBytes, bufferSizeBytes uint64, keyComparator comparator.KeyComparator) Configuration {
 	return Configuration{
-		directory:           directory,
-		segmentMaxSizeBytes: segmentMaxSizeBytes,
-		bufferSizeBytes:     bufferSizeBytes,
-		keyComparator:       keyComparator,
+		directory:             directory,
+		segmentMaxSizeBytes:   segmentMaxSizeBytes,
+		bufferSizeBytes:       bufferSizeBytes,
+		keyComparator:         keyComparator,
+		compactionOptions:     sst.DefaultCompactionOptions(),
+		syncPolicy:            SyncEveryCommit(),
+		blockCacheSizeBytes:   defaultBlockCacheSizeBytes,
+		compressionCodec:      sst.NoCompression{},
+		maxImmutableMemTables: defaultMaxImmutableMemTables,
 	}
 }
+
+func (configuration Configuration) WithCompactionOptions(options sst.CompactionOptions) Configuration {
+	configuration.compactionOptions = options
+	return configuration
+}
+
+func (configuration Configuration) WithSyncPolicy(syncPolicy SyncPolicy) Configuration {
+	configuration.syncPolicy = syncPolicy
+	return configuration
+}
+
+// WithValueThreshold keeps the values larger than the threshold in the value log, the memTable and ssTables hold a pointer to them.
+// A threshold of 0 keeps all the values in the memTable and ssTables, which is the default
+func (configuration Configuration) WithValueThreshold(thresholdBytes int) Configuration {
+	configuration.valueThresholdBytes = thresholdBytes
+	return configuration
+}
+
+// WithBlockCacheSize sets the capacity of the cache of ssTable blocks shared by all the ssTables, a size of 0 disables the cache
+func (configuration Configuration) WithBlockCacheSize(sizeBytes int64) Configuration {
+	configuration.blockCacheSizeBytes = sizeBytes
+	return configuration
+}
+
+// WithCompression compresses the blocks of the ssTables written with the codec, ssTables written with other codecs remain readable.
+// The default is sst.NoCompression
+func (configuration Configuration) WithCompression(compressionCodec sst.CompressionCodec) Configuration {
+	configuration.compressionCodec = compressionCodec
+	return configuration
+}
+
+// WithMaxImmutableMemTables sets the number of full memTables which may wait to be flushed, writes stall once the limit is reached.
+// A count below 1 allows 1 memTable to wait
+func (configuration Configuration) WithMaxImmutableMemTables(count int) Configuration {
+	configuration.maxImmutableMemTables = count
+	return configuration
+}
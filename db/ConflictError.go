@@ -0,0 +1,15 @@
+package db
+
+import (
+	"fmt"
+	"storage-engine-workshop/db/model"
+)
+
+// ConflictError is returned when a transaction commits after another transaction wrote a key which it read
+type ConflictError struct {
+	Key model.Slice
+}
+
+func (conflictError *ConflictError) Error() string {
+	return fmt.Sprintf("transaction conflict on key %v, it was written after the transaction started", conflictError.Key.AsString())
+}
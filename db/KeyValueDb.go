@@ -1,5 +1,11 @@
 package db
 
+import (
+	"storage-engine-workshop/storage/cache"
+	"storage-engine-workshop/storage/snapshot"
+	"storage-engine-workshop/storage/sst"
+)
+
 type KeyValueDb struct {
 	executor *RequestExecutor
 }
@@ -14,10 +20,45 @@ func NewKeyValueDb(configuration Configuration) (*KeyValueDb, error) {
 	}, nil
 }
 
-func (db *KeyValueDb) newTransaction() *Transaction {
+// NewTransaction returns a transaction which reads the state of the db as of its start, it is released by Commit or Discard
+func (db *KeyValueDb) NewTransaction() *Transaction {
 	return newTransaction(db.executor)
 }
 
-func (db *KeyValueDb) newReadonlyTransaction() ReadonlyTransaction {
-	return newReadonlyTransaction(db.executor)
+// NewReadonlyTransaction returns a transaction which reads the newest versions of keys as of each read
+func (db *KeyValueDb) NewReadonlyTransaction() ReadonlyTransaction {
+	return newReadonlyTransaction(db.executor.workSpace)
+}
+
+// NewReadonlyTransactionAt returns a transaction which observes the state of the db as of the snapshot,
+// the snapshot is released by the caller once the transaction is no longer used
+func (db *KeyValueDb) NewReadonlyTransactionAt(snapshot *snapshot.Snapshot) ReadonlyTransaction {
+	return newReadonlyTransactionAt(db.executor.workSpace, snapshot)
+}
+
+// NewSnapshot captures the current state of the db, the versions it sees are retained by compaction until it is released
+func (db *KeyValueDb) NewSnapshot() *snapshot.Snapshot {
+	return db.executor.workSpace.newSnapshot()
+}
+
+// Close completes the writes received before it, waits for the pending flushes and closes all the files of the db.
+// It returns the first error, the writes after it fail
+func (db *KeyValueDb) Close() error {
+	return <-db.executor.shutdown()
+}
+
+// CollectValueLogGarbage reclaims the space of the values in the oldest value log file which are no longer referenced
+func (db *KeyValueDb) CollectValueLogGarbage() error {
+	return <-db.executor.collectValueLogGarbage()
+}
+
+// BlockCacheStats returns the hits and misses of the block cache, which are 0 if the block cache is disabled
+func (db *KeyValueDb) BlockCacheStats() cache.Stats {
+	return db.executor.workSpace.blockCacheStats()
+}
+
+// Verify reads every ssTable and its bloom filter from disk and returns the corrupt files with their file id and offset,
+// the memTables and the WAL are not verified
+func (db *KeyValueDb) Verify() ([]*sst.CorruptionError, error) {
+	return db.executor.workSpace.verify()
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"storage-engine-workshop/db/model"
 	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/sst"
 	"strconv"
 	"sync"
 	"testing"
@@ -37,7 +38,7 @@ func TestPut1000KeysValuesAndGetByKeys(t *testing.T) {
 	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
 	db, _ := NewKeyValueDb(configuration)
 
-	txns := []*Transaction{db.newTransaction(), db.newTransaction(), db.newTransaction(), db.newTransaction(), db.newTransaction()}
+	txns := []*Transaction{db.NewTransaction(), db.NewTransaction(), db.NewTransaction(), db.NewTransaction(), db.NewTransaction()}
 	for transactionId := 0; transactionId < 5; transactionId++ {
 		for count := 1; count <= 200; count++ {
 			_ = txns[transactionId].Put(keyUsing(transactionId, count), valueUsing(transactionId, count))
@@ -50,7 +51,7 @@ func TestPut1000KeysValuesAndGetByKeys(t *testing.T) {
 
 	allowFlushingSSTable()
 
-	readonlyTxn := db.newReadonlyTransaction()
+	readonlyTxn := db.NewReadonlyTransaction()
 	for transactionId := 0; transactionId < 5; transactionId++ {
 		for count := 1; count <= 200; count++ {
 			getResult := readonlyTxn.Get(keyUsing(transactionId, count))
@@ -86,7 +87,7 @@ func TestPutsKeyValuePairsConcurrently(t *testing.T) {
 	for goroutineId := 1; goroutineId <= 10; goroutineId++ {
 		go func(id int) {
 			defer wg.Done()
-			txn := db.newTransaction()
+			txn := db.NewTransaction()
 			for index := 1; index <= 500; index++ {
 				_ = txn.Put(keyUsing(id, index), valueUsing(id, index))
 			}
@@ -97,7 +98,7 @@ func TestPutsKeyValuePairsConcurrently(t *testing.T) {
 	wg.Wait()
 	allowFlushingSSTableFor(5 * time.Second)
 
-	readonlyTxn := db.newReadonlyTransaction()
+	readonlyTxn := db.NewReadonlyTransaction()
 	for goroutineId := 1; goroutineId <= 10; goroutineId++ {
 		for count := 1; count <= 500; count++ {
 			getResult := readonlyTxn.Get(keyUsing(goroutineId, count))
@@ -117,3 +118,51 @@ func allowFlushingSSTable() {
 func allowFlushingSSTableFor(duration time.Duration) {
 	time.Sleep(duration)
 }
+
+func TestPutsUpdatesAndDeletesKeysWhileCompactingInBackground(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024 * 1024
+	const bufferMaxSizeBytes uint64 = 256
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	options := sst.DefaultCompactionOptions()
+	options.Level0FileCountTrigger = 2
+	options.Level1MaxSizeBytes = 1024
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithCompactionOptions(options)
+	db, _ := NewKeyValueDb(configuration)
+
+	keyUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Key-" + strconv.Itoa(count)))
+	}
+	for round := 1; round <= 3; round++ {
+		for count := 1; count <= 100; count++ {
+			txn := db.NewTransaction()
+			_ = txn.Put(keyUsing(count), model.NewSlice([]byte("Value-"+strconv.Itoa(round)+"-"+strconv.Itoa(count))))
+			if err := txn.Commit(); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	for count := 1; count <= 100; count = count + 2 {
+		txn := db.NewTransaction()
+		_ = txn.Delete(keyUsing(count))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	allowFlushingSSTable()
+
+	readonlyTxn := db.NewReadonlyTransaction()
+	for count := 1; count <= 100; count++ {
+		getResult := readonlyTxn.Get(keyUsing(count))
+		if count%2 == 1 && getResult.Exists {
+			t.Fatalf("Expected key %v to be deleted, received %v", keyUsing(count).AsString(), getResult.Value.AsString())
+		}
+		if expectedValue := "Value-3-" + strconv.Itoa(count); count%2 == 0 && getResult.Value.AsString() != expectedValue {
+			t.Fatalf("Expected %v, received %v", expectedValue, getResult.Value.AsString())
+		}
+	}
+}
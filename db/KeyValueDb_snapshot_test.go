@@ -0,0 +1,51 @@
+package db_test
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"storage-engine-workshop/db"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"testing"
+)
+
+func TestReadsTheStateOfTheDbAsOfASnapshotFromOutsideThePackage(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 512
+
+	directory, err := ioutil.TempDir(".", "db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	configuration := db.NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	keyValueDb, _ := db.NewKeyValueDb(configuration)
+
+	put := func(key, value string) {
+		txn := keyValueDb.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte(key)), model.NewSlice([]byte(value)))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	put("HDD", "Hard disk")
+
+	snapshot := keyValueDb.NewSnapshot()
+	defer snapshot.Release()
+
+	put("HDD", "Hard disk drive")
+	put("SDD", "Solid state")
+
+	snapshotTxn := keyValueDb.NewReadonlyTransactionAt(snapshot)
+	if getResult := snapshotTxn.Get(model.NewSlice([]byte("HDD"))); getResult.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", getResult.Value.AsString())
+	}
+	if getResult := snapshotTxn.Get(model.NewSlice([]byte("SDD"))); getResult.Exists {
+		t.Fatalf("Expected key %v committed after the snapshot to be missing, but was present", "SDD")
+	}
+	if getResult := keyValueDb.NewReadonlyTransaction().Get(model.NewSlice([]byte("HDD"))); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+}
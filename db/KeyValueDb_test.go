@@ -1,10 +1,14 @@
 package db
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"storage-engine-workshop/db/model"
 	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/sst"
 	"strconv"
 	"testing"
 )
@@ -19,14 +23,14 @@ func TestPutsKeysValuesAndGetByKeys(t *testing.T) {
 	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
 	db, _ := NewKeyValueDb(configuration)
 
-	txn := db.newTransaction()
+	txn := db.NewTransaction()
 	_ = txn.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value")))
 
 	if err := txn.Commit(); err != nil {
 		log.Fatal(err)
 	}
 
-	readonlyTxn := db.newReadonlyTransaction()
+	readonlyTxn := db.NewReadonlyTransaction()
 	getResult := readonlyTxn.Get(model.NewSlice([]byte("Key")))
 	if getResult.Value.AsString() != "Value" {
 		t.Fatalf("Expected %v, received %v", "Value", getResult.Value.AsString())
@@ -50,7 +54,7 @@ func TestPuts20KeysValuesAndGetByKeys(t *testing.T) {
 	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
 	db, _ := NewKeyValueDb(configuration)
 
-	txn := db.newTransaction()
+	txn := db.NewTransaction()
 	for count := 1; count <= 20; count++ {
 		err := txn.Put(keyUsing(count), valueUsing(count))
 		if err != nil {
@@ -63,7 +67,7 @@ func TestPuts20KeysValuesAndGetByKeys(t *testing.T) {
 
 	allowFlushingSSTable()
 
-	readonlyTxn := db.newReadonlyTransaction()
+	readonlyTxn := db.NewReadonlyTransaction()
 	for count := 1; count <= 20; count++ {
 		getResult := readonlyTxn.Get(keyUsing(count))
 		expectedValue := valueUsing(count)
@@ -73,3 +77,463 @@ func TestPuts20KeysValuesAndGetByKeys(t *testing.T) {
 		}
 	}
 }
+
+func TestPutsKeysValuesAndGetByKeysAfterRestart(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 32
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	db, _ := NewKeyValueDb(configuration)
+
+	txn := db.NewTransaction()
+	_ = txn.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value")))
+	if err := txn.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	dbAfterRestart, err := NewKeyValueDb(configuration)
+	if err != nil {
+		t.Fatalf("Expected no error while restarting the db but received %v", err)
+	}
+	readonlyTxn := dbAfterRestart.NewReadonlyTransaction()
+	getResult := readonlyTxn.Get(model.NewSlice([]byte("Key")))
+	if getResult.Value.AsString() != "Value" {
+		t.Fatalf("Expected %v, received %v", "Value", getResult.Value.AsString())
+	}
+}
+
+func TestClosesTheDbAndGetsKeysAfterReopening(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 32
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	db, _ := NewKeyValueDb(configuration)
+
+	for count := 1; count <= 20; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Expected no error while closing the db, received %v", err)
+	}
+	if memTables := db.executor.workSpace.flushManager.MemTables(); len(memTables) != 0 {
+		t.Fatalf("Expected no pending flushes after closing the db, received %v", len(memTables))
+	}
+	if err := db.executor.workSpace.wal.Sync(); err == nil {
+		t.Fatalf("Expected an error while syncing the WAL of a closed db, received none")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Expected no error while closing a closed db, received %v", err)
+	}
+
+	dbAfterReopening, err := NewKeyValueDb(configuration)
+	if err != nil {
+		t.Fatalf("Expected no error while reopening the db but received %v", err)
+	}
+	readonlyTxn := dbAfterReopening.NewReadonlyTransaction()
+	for count := 1; count <= 20; count++ {
+		expectedValue := "Value-" + strconv.Itoa(count)
+		if getResult := readonlyTxn.Get(model.NewSlice([]byte("Key-" + strconv.Itoa(count)))); getResult.Value.AsString() != expectedValue {
+			t.Fatalf("Expected %v, received %v", expectedValue, getResult.Value.AsString())
+		}
+	}
+}
+
+func TestCollectsValueLogGarbageAfterRestart(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	largeValue := model.NewSlice(make([]byte, 128))
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithValueThreshold(64)
+	db, _ := NewKeyValueDb(configuration)
+
+	txn := db.NewTransaction()
+	_ = txn.Put(model.NewSlice([]byte("Key")), largeValue)
+	if err := txn.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	dbAfterRestart, _ := NewKeyValueDb(configuration)
+	if err := dbAfterRestart.CollectValueLogGarbage(); err != nil {
+		t.Fatalf("Expected no error while collecting value log garbage but received %v", err)
+	}
+	readonlyTxn := dbAfterRestart.NewReadonlyTransaction()
+	getResult := readonlyTxn.Get(model.NewSlice([]byte("Key")))
+	if !getResult.Exists || getResult.Value.Size() != largeValue.Size() {
+		t.Fatalf("Expected a value of %v bytes, received %v bytes", largeValue.Size(), getResult.Value.Size())
+	}
+}
+
+func TestKeepsTheValuesOfALiveSnapshotReadableWhileCollectingValueLogGarbage(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	olderValue, newerValue := make([]byte, 128), make([]byte, 128)
+	olderValue[0], newerValue[0] = 'o', 'n'
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithValueThreshold(64)
+	db, _ := NewKeyValueDb(configuration)
+
+	txn := db.NewTransaction()
+	_ = txn.Put(model.NewSlice([]byte("Key")), model.NewSlice(olderValue))
+	if err := txn.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	dbAfterRestart, _ := NewKeyValueDb(configuration)
+	snapshot := dbAfterRestart.NewSnapshot()
+	scanIterator, _ := dbAfterRestart.NewReadonlyTransaction().Scan(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Kez")))
+
+	txn = dbAfterRestart.NewTransaction()
+	_ = txn.Put(model.NewSlice([]byte("Key")), model.NewSlice(newerValue))
+	if err := txn.Commit(); err != nil {
+		log.Fatal(err)
+	}
+	if err := dbAfterRestart.CollectValueLogGarbage(); err != nil {
+		t.Fatalf("Expected no error while collecting value log garbage but received %v", err)
+	}
+	if fileNames, _ := filepath.Glob(filepath.Join(directory, "vlog", "1.vlog")); len(fileNames) != 1 {
+		t.Fatalf("Expected the collected value log file to be kept while the snapshot is alive")
+	}
+
+	getResult := dbAfterRestart.NewReadonlyTransactionAt(snapshot).Get(model.NewSlice([]byte("Key")))
+	if getResult.Err != nil || getResult.Value.GetRawContent()[0] != 'o' {
+		t.Fatalf("Expected the older value to be readable at the snapshot, received %v and error %v", getResult.Value.AsString(), getResult.Err)
+	}
+	if !scanIterator.IsValid() || scanIterator.Value().Size() != len(olderValue) || scanIterator.Value().GetRawContent()[0] != 'o' {
+		t.Fatalf("Expected the older value to be readable by an iterator opened before the collection")
+	}
+	scanIterator.Close()
+	snapshot.Release()
+
+	if err := dbAfterRestart.CollectValueLogGarbage(); err != nil {
+		t.Fatalf("Expected no error while collecting value log garbage but received %v", err)
+	}
+	if fileNames, _ := filepath.Glob(filepath.Join(directory, "vlog", "1.vlog")); len(fileNames) != 0 {
+		t.Fatalf("Expected the collected value log file to be deleted once the snapshot is released")
+	}
+	getResult = dbAfterRestart.NewReadonlyTransaction().Get(model.NewSlice([]byte("Key")))
+	if getResult.Err != nil || getResult.Value.GetRawContent()[0] != 'n' {
+		t.Fatalf("Expected the newer value, received %v and error %v", getResult.Value.AsString(), getResult.Err)
+	}
+}
+
+func TestServesRepeatedReadsFromTheBlockCache(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	db, _ := NewKeyValueDb(configuration)
+
+	for count := 1; count <= 10; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	allowFlushingSSTable()
+
+	dbAfterRestart, _ := NewKeyValueDb(configuration)
+	readonlyTxn := dbAfterRestart.NewReadonlyTransaction()
+	for count := 1; count <= 3; count++ {
+		if getResult := readonlyTxn.Get(model.NewSlice([]byte("Key-1"))); getResult.Value.AsString() != "Value-1" {
+			t.Fatalf("Expected %v, received %v", "Value-1", getResult.Value.AsString())
+		}
+	}
+	if stats := dbAfterRestart.BlockCacheStats(); stats.Hits < 2 {
+		t.Fatalf("Expected repeated reads to hit the block cache, received %v hits and %v misses", stats.Hits, stats.Misses)
+	}
+}
+
+func TestGetsKeysFromCompressedSSTablesAfterRestart(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).
+		WithCompression(sst.SnappyCompression{})
+	db, _ := NewKeyValueDb(configuration)
+
+	for count := 1; count <= 10; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte(`{"value": "Value-`+strconv.Itoa(count)+`"}`)))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	allowFlushingSSTable()
+
+	dbAfterRestart, _ := NewKeyValueDb(configuration.WithCompression(sst.NoCompression{}))
+	readonlyTxn := dbAfterRestart.NewReadonlyTransaction()
+	for count := 1; count <= 10; count++ {
+		expected := `{"value": "Value-` + strconv.Itoa(count) + `"}`
+		if getResult := readonlyTxn.Get(model.NewSlice([]byte("Key-" + strconv.Itoa(count)))); getResult.Value.AsString() != expected {
+			t.Fatalf("Expected %v, received %v", expected, getResult.Value.AsString())
+		}
+	}
+}
+
+func TestReadsFromASnapshotIgnoringLaterCommits(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	db, _ := NewKeyValueDb(configuration)
+
+	put := func(key, value string) {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte(key)), model.NewSlice([]byte(value)))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	put("HDD", "Hard disk")
+	put("SDD", "Solid state")
+
+	snapshot := db.NewSnapshot()
+	defer snapshot.Release()
+
+	put("HDD", "Hard disk drive")
+	deleteTxn := db.NewTransaction()
+	_ = deleteTxn.Delete(model.NewSlice([]byte("SDD")))
+	if err := deleteTxn.Commit(); err != nil {
+		log.Fatal(err)
+	}
+	for count := 1; count <= 10; count++ {
+		put("Key-"+strconv.Itoa(count), "Value-"+strconv.Itoa(count))
+	}
+	allowFlushingSSTable()
+
+	snapshotTxn := db.NewReadonlyTransactionAt(snapshot)
+	expected := map[string]string{"HDD": "Hard disk", "SDD": "Solid state"}
+	for key, value := range expected {
+		if getResult := snapshotTxn.Get(model.NewSlice([]byte(key))); getResult.Value.AsString() != value {
+			t.Fatalf("Expected %v, received %v", value, getResult.Value.AsString())
+		}
+	}
+	if getResult := snapshotTxn.Get(model.NewSlice([]byte("Key-1"))); getResult.Exists {
+		t.Fatalf("Expected key %v committed after the snapshot to be missing, but was present", "Key-1")
+	}
+	iterator, _ := snapshotTxn.NewIterator()
+	defer iterator.Close()
+
+	var keys []string
+	for iterator.Seek(model.NilSlice()); iterator.IsValid(); iterator.Next() {
+		keys = append(keys, iterator.Key().AsString())
+	}
+	if len(keys) != 2 || keys[0] != "HDD" || keys[1] != "SDD" {
+		t.Fatalf("Expected keys %v, received %v", []string{"HDD", "SDD"}, keys)
+	}
+
+	readonlyTxn := db.NewReadonlyTransaction()
+	if getResult := readonlyTxn.Get(model.NewSlice([]byte("HDD"))); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+	if getResult := readonlyTxn.Get(model.NewSlice([]byte("SDD"))); getResult.Exists {
+		t.Fatalf("Expected key %v to be deleted, but was present", "SDD")
+	}
+}
+
+func TestContinuesSequencesAfterRestart(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	db, _ := NewKeyValueDb(configuration)
+	for count := 1; count <= 10; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	allowFlushingSSTable()
+
+	dbAfterRestart, _ := NewKeyValueDb(configuration)
+	if sequence := dbAfterRestart.NewSnapshot().Sequence(); sequence != 10 {
+		t.Fatalf("Expected the snapshot to be at sequence %v, received %v", 10, sequence)
+	}
+	txn := dbAfterRestart.NewTransaction()
+	_ = txn.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value-11")))
+	if err := txn.Commit(); err != nil {
+		log.Fatal(err)
+	}
+	if getResult := dbAfterRestart.NewReadonlyTransaction().Get(model.NewSlice([]byte("Key"))); getResult.Value.AsString() != "Value-11" {
+		t.Fatalf("Expected %v, received %v", "Value-11", getResult.Value.AsString())
+	}
+}
+
+func TestReturnsTheErrorWhileGettingAKeyFromACorruptSSTable(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).
+		WithBlockCacheSize(0)
+	db, _ := NewKeyValueDb(configuration)
+
+	for count := 1; count <= 10; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	allowFlushingSSTable()
+
+	fileNames, _ := filepath.Glob(filepath.Join(directory, "sst", "*.sst"))
+	for _, fileName := range fileNames {
+		_ = os.Truncate(fileName, 4)
+	}
+
+	readonlyTxn := db.NewReadonlyTransaction()
+	getResult := readonlyTxn.Get(model.NewSlice([]byte("Key-1")))
+	var corruptionError *sst.CorruptionError
+	if !errors.As(getResult.Err, &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while getting a key from a corrupt ssTable, received %v", getResult.Err)
+	}
+	if getResult.Exists {
+		t.Fatalf("Expected key %v to be missing, but was present with value %v", "Key-1", getResult.Value.AsString())
+	}
+}
+
+func TestReturnsTheErrorWhileScanningKeysFromACorruptSSTable(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).
+		WithBlockCacheSize(0)
+	db, _ := NewKeyValueDb(configuration)
+
+	for count := 1; count <= 10; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	allowFlushingSSTable()
+
+	fileNames, _ := filepath.Glob(filepath.Join(directory, "sst", "*.sst"))
+	for _, fileName := range fileNames {
+		file, _ := os.OpenFile(fileName, os.O_RDWR, 0644)
+		_, _ = file.WriteAt([]byte{0xff, 0xff}, 0)
+		_ = file.Close()
+	}
+
+	scanIterator, _ := db.NewReadonlyTransaction().Scan(model.NewSlice([]byte("Key-1")), model.NewSlice([]byte("Key-9")))
+	defer scanIterator.Close()
+
+	for ; scanIterator.IsValid(); scanIterator.Next() {
+	}
+	var corruptionError *sst.CorruptionError
+	if !errors.As(scanIterator.Err(), &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while scanning keys from a corrupt ssTable, received %v", scanIterator.Err())
+	}
+}
+
+func TestStopsScanningKeysWhoseValuesCanNotBeReadFromTheValueLog(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 10 * 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithValueThreshold(16)
+	db, _ := NewKeyValueDb(configuration)
+
+	for count := 1; count <= 5; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte(fmt.Sprintf("Value-%v-%0100d", count, count))))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fileNames, _ := filepath.Glob(filepath.Join(directory, "vlog", "*.vlog"))
+	for _, fileName := range fileNames {
+		_ = os.Truncate(fileName, 0)
+	}
+
+	scanIterator, _ := db.NewReadonlyTransaction().Scan(model.NewSlice([]byte("Key-1")), model.NewSlice([]byte("Key-9")))
+	defer scanIterator.Close()
+
+	for ; scanIterator.IsValid(); scanIterator.Next() {
+		t.Fatalf("Expected the iterator to be invalid for key %v whose value can not be read, received value %v", scanIterator.Key().AsString(), scanIterator.Value().AsString())
+	}
+	if scanIterator.Err() == nil {
+		t.Fatalf("Expected an error while scanning keys whose values can not be read from the value log, received none")
+	}
+}
+
+func TestVerifiesTheSSTablesOfTheDbReportingTheCorruptFile(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).
+		WithBlockCacheSize(0)
+	db, _ := NewKeyValueDb(configuration)
+
+	for count := 1; count <= 10; count++ {
+		txn := db.NewTransaction()
+		_ = txn.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		if err := txn.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	allowFlushingSSTable()
+
+	corruptions, err := db.Verify()
+	if err != nil || len(corruptions) != 0 {
+		t.Fatalf("Expected no corruptions and no error while verifying the db, received %v and %v", corruptions, err)
+	}
+
+	fileNames, _ := filepath.Glob(filepath.Join(directory, "sst", "*.sst"))
+	if len(fileNames) == 0 {
+		t.Fatalf("Expected the db to have ssTables, received none")
+	}
+	file, _ := os.OpenFile(fileNames[0], os.O_RDWR, 0644)
+	_, _ = file.WriteAt([]byte{0xff, 0xff}, 0)
+	_ = file.Close()
+
+	corruptions, err = db.Verify()
+	if err != nil {
+		t.Fatalf("Expected no error while verifying the db, received %v", err)
+	}
+	if len(corruptions) != 1 || corruptions[0].FileName != fileNames[0] || corruptions[0].Offset != 0 {
+		t.Fatalf("Expected %v corruption in %v at offset 0, received %v", 1, fileNames[0], corruptions)
+	}
+}
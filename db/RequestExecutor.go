@@ -2,68 +2,224 @@ package db
 
 import (
 	"errors"
+	"log"
 	"storage-engine-workshop/db/model"
+	"sync"
+	"time"
 )
 
+// RequestExecutor serializes all the writes through a single goroutine.
+// Put requests which are pending together are written to the WAL with a single write and sync, and applied in order.
+// Reads do not go through the executor, they are served by the Workspace directly.
+// The goroutine returns after the shutdown, the requests queued after the shutdown fail.
 type RequestExecutor struct {
 	requestChannel chan interface{}
 	workSpace      *Workspace
+	lock           sync.Mutex
+	closed         bool
+	senders        sync.WaitGroup
 }
 
+const maxPutRequestsInGroup = 256
+
 func newRequestExecutor(workSpace *Workspace) *RequestExecutor {
 	executor := &RequestExecutor{
-		requestChannel: make(chan interface{}),
+		requestChannel: make(chan interface{}, maxPutRequestsInGroup),
 		workSpace:      workSpace,
 	}
-	//Assignment:Concurrency:1:init the executor. Init should create a goroutine to "continuously" read from a request channel
+	executor.init()
 	return executor
 }
 
 func (executor *RequestExecutor) init() {
-	put := func(putRequest PutRequest) {
-		//Assignment:Concurrency:2:execute the put request and send the response back
-		putRequest.ResponseChannel <- errors.New("please complete the assignment")
-		close(putRequest.ResponseChannel)
+	syncPolicy := executor.workSpace.configuration.syncPolicy
+	//a put request whose read set conflicts with an earlier commit, or with an earlier put request in the group, fails without being written
+	put := func(putRequests []PutRequest) {
+		var acceptedRequests []PutRequest
+		var batches []*Batch
+		for _, putRequest := range putRequests {
+			if err := executor.workSpace.checkConflicts(putRequest.ReadSet, putRequest.StartSequence, batches); err != nil {
+				putRequest.ResponseChannel <- err
+				close(putRequest.ResponseChannel)
+				continue
+			}
+			acceptedRequests = append(acceptedRequests, putRequest)
+			batches = append(batches, putRequest.Batch)
+		}
+		if len(batches) == 0 {
+			return
+		}
+		errs := executor.workSpace.putAll(batches)
+		for index, putRequest := range acceptedRequests {
+			putRequest.ResponseChannel <- errs[index]
+			close(putRequest.ResponseChannel)
+		}
 	}
-	get := func(getRequest GetRequest) {
-		//Assignment:Concurrency:3:execute the get request and send the response back
-		getRequest.ResponseChannel <- model.GetResult{}
-		close(getRequest.ResponseChannel)
+	collectValueLogGarbage := func(request ValueLogGarbageCollectionRequest) {
+		request.ResponseChannel <- executor.workSpace.collectValueLogGarbage()
+		close(request.ResponseChannel)
 	}
-	multiGet := func(multiGetRequest MultiGetRequest) {
-		//Assignment:Concurrency:4:execute the multiGet request and send the response back
-		multiGetRequest.ResponseChannel <- []model.GetResult{}
-		close(multiGetRequest.ResponseChannel)
+	//collect coalesces the put requests which are pending after the first one,
+	//group commit additionally waits up to its max delay for more put requests.
+	//A request which is not a put request ends the group, it is returned to be handled after the group
+	collect := func(putRequest PutRequest) ([]PutRequest, interface{}) {
+		putRequests := []PutRequest{putRequest}
+		add := func(request interface{}) bool {
+			if putRequest, ok := request.(PutRequest); ok {
+				putRequests = append(putRequests, putRequest)
+				return true
+			}
+			return false
+		}
+		if syncPolicy.kind != groupCommit {
+			for len(putRequests) < maxPutRequestsInGroup {
+				select {
+				case request := <-executor.requestChannel:
+					if !add(request) {
+						return putRequests, request
+					}
+				default:
+					return putRequests, nil
+				}
+			}
+			return putRequests, nil
+		}
+		timer := time.NewTimer(syncPolicy.maxDelay)
+		defer timer.Stop()
+		for len(putRequests) < maxPutRequestsInGroup {
+			select {
+			case request := <-executor.requestChannel:
+				if !add(request) {
+					return putRequests, request
+				}
+			case <-timer.C:
+				return putRequests, nil
+			}
+		}
+		return putRequests, nil
+	}
+	var syncTicker *time.Ticker
+	var syncTick <-chan time.Time
+	if syncPolicy.kind == syncEveryInterval {
+		syncTicker = time.NewTicker(syncPolicy.interval)
+		syncTick = syncTicker.C
+	}
+	//shutdown stops the sync ticker and closes the workspace, the commits acknowledged since the last tick become durable
+	stopped := false
+	shutdown := func(request ShutdownRequest) {
+		executor.lock.Lock()
+		executor.closed = true
+		executor.lock.Unlock()
+		if syncTicker != nil {
+			syncTicker.Stop()
+		}
+		stopped = true
+		request.ResponseChannel <- executor.workSpace.close()
+		close(request.ResponseChannel)
+	}
+	reject := func(request interface{}) {
+		switch request := request.(type) {
+		case PutRequest:
+			request.ResponseChannel <- errors.New("db is closed")
+			close(request.ResponseChannel)
+		case ValueLogGarbageCollectionRequest:
+			request.ResponseChannel <- errors.New("db is closed")
+			close(request.ResponseChannel)
+		case ShutdownRequest:
+			close(request.ResponseChannel)
+		}
+	}
+	//drain fails the requests queued after the shutdown, including the ones whose senders are still queueing them
+	drain := func() {
+		sendersDone := make(chan struct{})
+		go func() {
+			executor.senders.Wait()
+			close(sendersDone)
+		}()
+		for {
+			select {
+			case request := <-executor.requestChannel:
+				reject(request)
+			case <-sendersDone:
+				for {
+					select {
+					case request := <-executor.requestChannel:
+						reject(request)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}
+	var handle func(request interface{})
+	handle = func(request interface{}) {
+		switch request := request.(type) {
+		case PutRequest:
+			putRequests, next := collect(request)
+			put(putRequests)
+			if next != nil {
+				handle(next)
+			}
+		case ValueLogGarbageCollectionRequest:
+			collectValueLogGarbage(request)
+		case ShutdownRequest:
+			shutdown(request)
+		}
 	}
 
 	go func() {
-		for {
-			request := <-executor.requestChannel
-			if putRequest, ok := request.(PutRequest); ok {
-				put(putRequest)
-			} else if getRequest, ok := request.(GetRequest); ok {
-				get(getRequest)
-			} else if multiGetRequest, ok := request.(MultiGetRequest); ok {
-				multiGet(multiGetRequest)
+		for !stopped {
+			select {
+			case request := <-executor.requestChannel:
+				handle(request)
+			case <-syncTick:
+				if err := executor.workSpace.syncWAL(); err != nil {
+					log.Default().Println("Error while syncing the WAL " + err.Error())
+				}
 			}
 		}
+		drain()
 	}()
 }
 
 func (executor *RequestExecutor) put(batch *Batch) chan error {
+	return executor.commit(batch, nil, 0)
+}
+
+// commit puts the batch if none of the keys in the readSet is written after the startSequence
+func (executor *RequestExecutor) commit(batch *Batch, readSet []model.Slice, startSequence uint64) chan error {
 	responseChannel := make(chan error)
-	executor.requestChannel <- PutRequest{Batch: batch, ResponseChannel: responseChannel}
-	return responseChannel
+	return executor.send(PutRequest{Batch: batch, ReadSet: readSet, StartSequence: startSequence, ResponseChannel: responseChannel}, responseChannel)
 }
 
-func (executor *RequestExecutor) get(key model.Slice) chan model.GetResult {
-	responseChannel := make(chan model.GetResult)
-	executor.requestChannel <- GetRequest{Key: key, ResponseChannel: responseChannel}
-	return responseChannel
+func (executor *RequestExecutor) collectValueLogGarbage() chan error {
+	responseChannel := make(chan error)
+	return executor.send(ValueLogGarbageCollectionRequest{ResponseChannel: responseChannel}, responseChannel)
 }
 
-func (executor *RequestExecutor) multiGet(keys []model.Slice) chan []model.GetResult {
-	responseChannel := make(chan []model.GetResult)
-	executor.requestChannel <- MultiGetRequest{Keys: keys, ResponseChannel: responseChannel}
+// shutdown completes the requests received before it, stops the executor and closes the workspace
+func (executor *RequestExecutor) shutdown() chan error {
+	responseChannel := make(chan error)
+	return executor.send(ShutdownRequest{ResponseChannel: responseChannel}, responseChannel)
+}
+
+// send queues the request, a request sent after the shutdown fails without being queued and a repeated shutdown succeeds
+func (executor *RequestExecutor) send(request interface{}, responseChannel chan error) chan error {
+	executor.lock.Lock()
+	if executor.closed {
+		executor.lock.Unlock()
+		rejectedChannel := make(chan error, 1)
+		if _, ok := request.(ShutdownRequest); !ok {
+			rejectedChannel <- errors.New("db is closed")
+		}
+		close(rejectedChannel)
+		return rejectedChannel
+	}
+	executor.senders.Add(1)
+	executor.lock.Unlock()
+	defer executor.senders.Done()
+
+	executor.requestChannel <- request
 	return responseChannel
 }
@@ -2,7 +2,9 @@ package db
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
 	"storage-engine-workshop/db/model"
 	"storage-engine-workshop/storage/comparator"
 	"strconv"
@@ -12,12 +14,16 @@ import (
 )
 
 func initRequestExecutor() (*RequestExecutor, string) {
+	return initRequestExecutorWith(SyncEveryCommit())
+}
+
+func initRequestExecutorWith(syncPolicy SyncPolicy) (*RequestExecutor, string) {
 	const segmentMaxSizeBytes uint64 = 32
 	const bufferMaxSizeBytes uint64 = 1024
 
 	directory := tempDirectory()
 
-	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithSyncPolicy(syncPolicy)
 	workSpace, _ := newWorkSpace(configuration)
 
 	return newRequestExecutor(workSpace), directory
@@ -40,7 +46,7 @@ func TestPutFollowedByGet(t *testing.T) {
 
 	go func() {
 		defer wg.Done()
-		getResult := <-executor.get(model.NewSlice([]byte("Company")))
+		getResult := executor.workSpace.get(model.NewSlice([]byte("Company")), model.LatestSequence)
 		if getResult.Value.AsString() != "TW" {
 			t.Errorf(fmt.Sprintf("Expected value to be %v, received %v", "TW", getResult.Value.AsString()))
 		}
@@ -64,7 +70,7 @@ func TestPutAndGetConcurrently(t *testing.T) {
 
 	go func() {
 		defer wg.Done()
-		getResult := <-executor.get(model.NewSlice([]byte("Company")))
+		getResult := executor.workSpace.get(model.NewSlice([]byte("Company")), model.LatestSequence)
 		if getResult.Exists && getResult.Value.AsString() != "TW" {
 			t.Errorf(fmt.Sprintf("Expected value to be %v, received %v", "TW", getResult.Value.AsString()))
 		}
@@ -95,7 +101,7 @@ func TestMultiGetFollowedByPut(t *testing.T) {
 			"Company": "TW",
 			"Field":   "Storage engine",
 		}
-		multiGetResult := <-executor.multiGet([]model.Slice{model.NewSlice([]byte("Company")), model.NewSlice([]byte("Field"))})
+		multiGetResult := executor.workSpace.multiGet([]model.Slice{model.NewSlice([]byte("Company")), model.NewSlice([]byte("Field"))}, model.LatestSequence)
 		for _, result := range multiGetResult {
 			if result.Value.AsString() != expectedValueByKey[result.Key.AsString()] {
 				t.Errorf(fmt.Sprintf("Expected value to be %v, received %v", expectedValueByKey[result.Key.AsString()], result.Value.AsString()))
@@ -134,7 +140,7 @@ func TestPutMultipleConcurrentBatches(t *testing.T) {
 
 	for goroutineId := 1; goroutineId <= 10; goroutineId++ {
 		for index := 1; index <= 200; index++ {
-			getResult := <-executor.get(keyUsing(goroutineId, index))
+			getResult := executor.workSpace.get(keyUsing(goroutineId, index), model.LatestSequence)
 			expectedValue := valueUsing(goroutineId, index)
 			if getResult.Value.AsString() != expectedValue.AsString() {
 				t.Fatalf("Expected value to be %v, received %v", expectedValue.AsString(), getResult.Value.AsString())
@@ -165,7 +171,7 @@ func TestPutAndMultiGetConcurrently(t *testing.T) {
 			"Company": "TW",
 			"Field":   "Storage engine",
 		}
-		multiGetResult := <-executor.multiGet([]model.Slice{model.NewSlice([]byte("Company")), model.NewSlice([]byte("Field"))})
+		multiGetResult := executor.workSpace.multiGet([]model.Slice{model.NewSlice([]byte("Company")), model.NewSlice([]byte("Field"))}, model.LatestSequence)
 		for _, result := range multiGetResult {
 			if result.Exists && result.Value.AsString() != expectedValueByKey[result.Key.AsString()] {
 				t.Errorf(fmt.Sprintf("Expected value to be %v, received %v", expectedValueByKey[result.Key.AsString()], result.Value.AsString()))
@@ -175,3 +181,120 @@ func TestPutAndMultiGetConcurrently(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestPutConcurrentBatchesWithSyncPolicies(t *testing.T) {
+	for _, syncPolicy := range []SyncPolicy{GroupCommit(5 * time.Millisecond), SyncEveryInterval(5 * time.Millisecond)} {
+		executor, directory := initRequestExecutorWith(syncPolicy)
+
+		var wg sync.WaitGroup
+		wg.Add(20)
+		for count := 1; count <= 20; count++ {
+			go func(count int) {
+				defer wg.Done()
+				batch := NewBatch()
+				batch.add(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+				if err := <-executor.put(batch); err != nil {
+					t.Errorf("Expected no error while putting a batch, received %v", err)
+				}
+			}(count)
+		}
+		wg.Wait()
+
+		for count := 1; count <= 20; count++ {
+			getResult := executor.workSpace.get(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.LatestSequence)
+			if getResult.Value.AsString() != "Value-"+strconv.Itoa(count) {
+				t.Errorf("Expected value to be %v, received %v", "Value-"+strconv.Itoa(count), getResult.Value.AsString())
+			}
+		}
+		os.RemoveAll(directory)
+	}
+}
+
+func TestFailsPutsAfterShuttingDownTheExecutorWithASyncInterval(t *testing.T) {
+	executor, directory := initRequestExecutorWith(SyncEveryInterval(5 * time.Millisecond))
+	defer os.RemoveAll(directory)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	if err := <-executor.put(batch); err != nil {
+		t.Fatalf("Expected no error while putting a batch, received %v", err)
+	}
+	if err := <-executor.shutdown(); err != nil {
+		t.Fatalf("Expected no error while shutting down the executor, received %v", err)
+	}
+	laterBatch := NewBatch()
+	laterBatch.add(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+	if err := <-executor.put(laterBatch); err == nil {
+		t.Fatalf("Expected an error while putting a batch after shutting down the executor, received none")
+	}
+	if err := <-executor.shutdown(); err != nil {
+		t.Fatalf("Expected no error while shutting down a stopped executor, received %v", err)
+	}
+}
+
+func TestFailsPutRequestsQueuedAfterAShutdownRequest(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 32
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workSpace, _ := newWorkSpace(configuration)
+	executor := &RequestExecutor{requestChannel: make(chan interface{}, maxPutRequestsInGroup), workSpace: workSpace}
+
+	shutdownChannel := executor.shutdown()
+	var responseChannels []chan error
+	for count := 1; count <= 10; count++ {
+		batch := NewBatch()
+		batch.add(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		responseChannels = append(responseChannels, executor.put(batch))
+	}
+	executor.init()
+
+	if err := <-shutdownChannel; err != nil {
+		t.Fatalf("Expected no error while shutting down the executor, received %v", err)
+	}
+	for _, responseChannel := range responseChannels {
+		if err := <-responseChannel; err == nil {
+			t.Fatalf("Expected an error for a put request queued after the shutdown request, received none")
+		}
+	}
+	if getResult := workSpace.get(model.NewSlice([]byte("Key-1")), model.LatestSequence); getResult.Exists {
+		t.Fatalf("Expected key %v queued after the shutdown to be missing, but was present", "Key-1")
+	}
+}
+
+func TestCoalescesPendingPutRequestsIntoASingleWALWrite(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 32
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workSpace, _ := newWorkSpace(configuration)
+	executor := &RequestExecutor{requestChannel: make(chan interface{}, maxPutRequestsInGroup), workSpace: workSpace}
+
+	var responseChannels []chan error
+	for count := 1; count <= 10; count++ {
+		batch := NewBatch()
+		batch.add(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		responseChannels = append(responseChannels, executor.put(batch))
+	}
+	executor.init()
+
+	for _, responseChannel := range responseChannels {
+		if err := <-responseChannel; err != nil {
+			t.Fatalf("Expected no error while putting a batch, received %v", err)
+		}
+	}
+	transactionalEntries, _ := workSpace.wal.ReadAll()
+	if len(transactionalEntries) != 10 {
+		t.Fatalf("Expected %v transactional entries, received %v", 10, len(transactionalEntries))
+	}
+	//each transaction is larger than a segment, transactions written one at a time would roll over the segment every time
+	if segmentFiles, _ := ioutil.ReadDir(path.Join(directory, "wal")); len(segmentFiles) != 1 {
+		t.Fatalf("Expected all the transactions to be written to %v segment, received %v segments", 1, len(segmentFiles))
+	}
+}
@@ -1,20 +1,20 @@
 package db
 
-import (
-	"storage-engine-workshop/db/model"
-)
+import "storage-engine-workshop/db/model"
 
+// PutRequest fails with a ConflictError if any key in the ReadSet is written after the StartSequence
 type PutRequest struct {
 	Batch           *Batch
+	ReadSet         []model.Slice
+	StartSequence   uint64
 	ResponseChannel chan error
 }
 
-type GetRequest struct {
-	Key             model.Slice
-	ResponseChannel chan model.GetResult
+type ValueLogGarbageCollectionRequest struct {
+	ResponseChannel chan error
 }
 
-type MultiGetRequest struct {
-	Keys            []model.Slice
-	ResponseChannel chan []model.GetResult
+// ShutdownRequest stops the RequestExecutor, the requests received after it fail
+type ShutdownRequest struct {
+	ResponseChannel chan error
 }
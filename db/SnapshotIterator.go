@@ -0,0 +1,21 @@
+package db
+
+import "storage-engine-workshop/storage/iterator"
+
+// snapshotIterator releases the snapshot which it reads at once it is closed
+type snapshotIterator struct {
+	iterator.Iterator
+	release func()
+}
+
+func newSnapshotIterator(iterator iterator.Iterator, release func()) *snapshotIterator {
+	return &snapshotIterator{
+		Iterator: iterator,
+		release:  release,
+	}
+}
+
+func (snapshotIterator *snapshotIterator) Close() {
+	snapshotIterator.Iterator.Close()
+	snapshotIterator.release()
+}
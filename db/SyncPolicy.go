@@ -0,0 +1,41 @@
+package db
+
+import "time"
+
+type syncKind int
+
+const (
+	syncEveryCommit syncKind = iota
+	groupCommit
+	syncEveryInterval
+)
+
+// SyncPolicy decides when the WAL is synced to disk and hence when a commit is acknowledged
+type SyncPolicy struct {
+	kind     syncKind
+	maxDelay time.Duration
+	interval time.Duration
+}
+
+// SyncEveryCommit syncs the WAL before acknowledging each commit
+func SyncEveryCommit() SyncPolicy {
+	return SyncPolicy{kind: syncEveryCommit}
+}
+
+// GroupCommit waits up to maxDelay for concurrent commits and syncs the WAL once before acknowledging all of them
+func GroupCommit(maxDelay time.Duration) SyncPolicy {
+	return SyncPolicy{kind: groupCommit, maxDelay: maxDelay}
+}
+
+// SyncEveryInterval acknowledges a commit once it is written to the WAL and syncs the WAL every interval,
+// commits acknowledged within the last interval may be lost on a crash. An interval which is not positive syncs every commit
+func SyncEveryInterval(interval time.Duration) SyncPolicy {
+	if interval <= 0 {
+		return SyncEveryCommit()
+	}
+	return SyncPolicy{kind: syncEveryInterval, interval: interval}
+}
+
+func (syncPolicy SyncPolicy) syncsOnCommit() bool {
+	return syncPolicy.kind != syncEveryInterval
+}
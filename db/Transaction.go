@@ -4,31 +4,48 @@ import (
 	"errors"
 	"fmt"
 	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/iterator"
+	"storage-engine-workshop/storage/snapshot"
 )
 
+// Transaction reads the state of the db as of its start, along with its own writes.
+// Commit fails with a ConflictError if a key read by the transaction is written by another transaction after the start
 type Transaction struct {
 	executor *RequestExecutor
 	batch    *Batch
+	snapshot *snapshot.Snapshot
+	readSet  []model.Slice
 }
 
+// ReadonlyTransaction reads the newest versions of keys as of its snapshot, a transaction without a snapshot reads the newest versions as of each read
 type ReadonlyTransaction struct {
-	executor *RequestExecutor
+	workspace *Workspace
+	snapshot  *snapshot.Snapshot
 }
 
 const (
-	maxSizeAllowedBytes uint16 = 65535
+	maxSizeAllowedBytes uint32 = 256 * 1024 * 1024
 )
 
 func newTransaction(executor *RequestExecutor) *Transaction {
 	return &Transaction{
 		executor: executor,
 		batch:    NewBatch(),
+		snapshot: executor.workSpace.newSnapshot(),
 	}
 }
 
-func newReadonlyTransaction(executor *RequestExecutor) ReadonlyTransaction {
+func newReadonlyTransaction(workspace *Workspace) ReadonlyTransaction {
 	return ReadonlyTransaction{
-		executor: executor,
+		workspace: workspace,
+	}
+}
+
+// newReadonlyTransactionAt returns a transaction which observes the state of the db as of the snapshot
+func newReadonlyTransactionAt(workspace *Workspace, snapshot *snapshot.Snapshot) ReadonlyTransaction {
+	return ReadonlyTransaction{
+		workspace: workspace,
+		snapshot:  snapshot,
 	}
 }
 
@@ -36,23 +53,88 @@ func (txn *Transaction) Put(key, value model.Slice) error {
 	if txn.batch.isTotalSizeGreaterThan(maxSizeAllowedBytes) {
 		return errors.New(fmt.Sprintf("can not add more than the total key/value pair size %v in a transaction", maxSizeAllowedBytes))
 	}
-	//Assignment:Transaction:1:add the key/value to the batch
+	txn.batch.add(key, value)
 	return nil
 }
 
+func (txn *Transaction) Delete(key model.Slice) error {
+	if txn.batch.isTotalSizeGreaterThan(maxSizeAllowedBytes) {
+		return errors.New(fmt.Sprintf("can not add more than the total key/value pair size %v in a transaction", maxSizeAllowedBytes))
+	}
+	txn.batch.delete(key)
+	return nil
+}
+
+// Get returns the last write of the key in the transaction, or else the newest version of the key as of the start of the transaction
+func (txn *Transaction) Get(key model.Slice) model.GetResult {
+	if keyValuePair, ok := txn.batch.get(key, txn.executor.workSpace.configuration.keyComparator); ok {
+		if keyValuePair.Deleted {
+			return model.GetResult{Key: key, Value: model.NilSlice(), Exists: false, Deleted: true}
+		}
+		return model.GetResult{Key: key, Value: keyValuePair.Value, Exists: true}
+	}
+	txn.readSet = append(txn.readSet, key)
+	return txn.executor.workSpace.get(key, txn.snapshot.Sequence())
+}
+
 func (txn *Transaction) Commit() error {
+	defer txn.Discard()
 	if txn.batch.isEmpty() {
 		return errors.New("nothing to commit, put key/value before committing")
 	}
-	//Assignment:Transaction:2:ask the request executor to handle the batch
-	return errors.New("complete the assignment")
+	return <-txn.executor.commit(txn.batch, txn.readSet, txn.snapshot.Sequence())
+}
+
+// Discard releases the snapshot of the transaction without committing it, a committed transaction is discarded by Commit
+func (txn *Transaction) Discard() {
+	txn.snapshot.Release()
 }
 
+// Get returns the newest version of the key as of the sequence of the transaction.
+// The result carries an sst.IOError or an sst.CorruptionError if the key can not be read
 func (txn ReadonlyTransaction) Get(key model.Slice) model.GetResult {
-	//Assignment:Transaction:3:ask the request executor to handle the get
-	return model.GetResult{}
+	snapshot, release := txn.pin()
+	defer release()
+	return txn.workspace.get(key, snapshot.Sequence())
 }
 
+// MultiGet returns the results positionally aligned with the keys, the result of a key carries the error which prevented reading it
 func (txn ReadonlyTransaction) MultiGet(keys []model.Slice) []model.GetResult {
-	return <-txn.executor.multiGet(keys)
+	snapshot, release := txn.pin()
+	defer release()
+	return txn.workspace.multiGet(keys, snapshot.Sequence())
+}
+
+// NewIterator returns an iterator which holds the snapshot it reads at until it is closed
+func (txn ReadonlyTransaction) NewIterator() (iterator.Iterator, error) {
+	snapshot, release := txn.pin()
+	mergedIterator, err := txn.workspace.newIterator(snapshot.Sequence())
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return newSnapshotIterator(mergedIterator, release), nil
+}
+
+// Scan returns an iterator positioned at begin (inclusive) which stops before end (exclusive).
+// The iterator becomes invalid if a key can not be read, Err tells such an iterator from an exhausted one
+func (txn ReadonlyTransaction) Scan(begin, end model.Slice) (iterator.Iterator, error) {
+	mergedIterator, err := txn.NewIterator()
+	if err != nil {
+		return nil, err
+	}
+	boundedIterator := iterator.NewBoundedIterator(mergedIterator, end, txn.workspace.configuration.keyComparator)
+	boundedIterator.Seek(begin)
+	return boundedIterator, nil
+}
+
+// pin returns the snapshot which a read of the transaction is at, along with the function to release it once the read is done.
+// A transaction without a snapshot takes one for each read, which keeps the value log files that the read may resolve values from
+// from being deleted by garbage collection
+func (txn ReadonlyTransaction) pin() (*snapshot.Snapshot, func()) {
+	if txn.snapshot != nil {
+		return txn.snapshot, func() {}
+	}
+	snapshot := txn.workspace.newSnapshot()
+	return snapshot, snapshot.Release
 }
@@ -1,8 +1,11 @@
 package db
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
 	"strconv"
 	"sync"
 	"testing"
@@ -30,7 +33,7 @@ func TestPutsAKeyValuePairAndGetsByKey(t *testing.T) {
 	_ = transaction.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value")))
 	_ = transaction.Commit()
 
-	readonlyTxn := newReadonlyTransaction(executor)
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
 	if getResult := readonlyTxn.Get(model.NewSlice([]byte("Key"))); getResult.Value.AsString() != "Value" {
 		t.Fatalf("Expected %v, received %v", "Value", getResult.Value.AsString())
 	}
@@ -60,7 +63,7 @@ func TestPutsMultipleKeyValuePairsInDifferentGoroutines(t *testing.T) {
 	}
 	wg.Wait()
 
-	readonlyTxn := newReadonlyTransaction(executor)
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
 	for count := 1; count <= 10; count++ {
 		getResult := readonlyTxn.Get(keyUsing(count))
 		expectedValue := valueUsing(count)
@@ -70,3 +73,268 @@ func TestPutsMultipleKeyValuePairsInDifferentGoroutines(t *testing.T) {
 		}
 	}
 }
+
+func TestDeletesAKeyAndGetsByKey(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	transaction := newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value")))
+	_ = transaction.Commit()
+
+	transaction = newTransaction(executor)
+	_ = transaction.Delete(model.NewSlice([]byte("Key")))
+	_ = transaction.Commit()
+
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
+	if getResult := readonlyTxn.Get(model.NewSlice([]byte("Key"))); getResult.Exists {
+		t.Fatalf("Expected key %v to be deleted, but was present with value %v", "Key", getResult.Value.AsString())
+	}
+}
+
+func TestUpdatesAKeyInALaterTransactionAndGetsByKey(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	transaction := newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value")))
+	_ = transaction.Commit()
+
+	transaction = newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Updated value")))
+	_ = transaction.Commit()
+
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
+	if getResult := readonlyTxn.Get(model.NewSlice([]byte("Key"))); getResult.Value.AsString() != "Updated value" {
+		t.Fatalf("Expected %v, received %v", "Updated value", getResult.Value.AsString())
+	}
+}
+
+func TestScansKeysInARange(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	keyUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Key-" + strconv.Itoa(count)))
+	}
+	valueUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Value-" + strconv.Itoa(count)))
+	}
+
+	transaction := newTransaction(executor)
+	for count := 1; count <= 9; count++ {
+		_ = transaction.Put(keyUsing(count), valueUsing(count))
+	}
+	_ = transaction.Commit()
+
+	transaction = newTransaction(executor)
+	_ = transaction.Delete(keyUsing(4))
+	_ = transaction.Commit()
+
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
+	iterator, err := readonlyTxn.Scan(keyUsing(3), keyUsing(7))
+	if err != nil {
+		t.Fatalf("Expected no error while scanning but received %v", err)
+	}
+	defer iterator.Close()
+
+	for _, count := range []int{3, 5, 6} {
+		if !iterator.IsValid() {
+			t.Fatalf("Expected iterator to be valid at key %v, but was not", keyUsing(count).AsString())
+		}
+		if iterator.Value().AsString() != valueUsing(count).AsString() {
+			t.Fatalf("Expected %v, received %v", valueUsing(count).AsString(), iterator.Value().AsString())
+		}
+		iterator.Next()
+	}
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
+
+func TestPutsAValueLargerThan64KBAndGetsByKey(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	value := make([]byte, 2*1024*1024)
+	for index := range value {
+		value[index] = byte('a' + index%26)
+	}
+	transaction := newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Blob")), model.NewSlice(value))
+	if err := transaction.Commit(); err != nil {
+		t.Fatalf("Expected no error while committing a transaction larger than 64KB, received %v", err)
+	}
+
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
+	if getResult := readonlyTxn.Get(model.NewSlice([]byte("Blob"))); getResult.Value.AsString() != string(value) {
+		t.Fatalf("Expected value of size %v, received a value of size %v", len(value), getResult.Value.Size())
+	}
+}
+
+func TestGetsTheWritesOfTheTransactionBeforeCommitting(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	transaction := newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value")))
+	_ = transaction.Commit()
+
+	transaction = newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Updated value")))
+	if getResult := transaction.Get(model.NewSlice([]byte("Key"))); getResult.Value.AsString() != "Updated value" {
+		t.Fatalf("Expected %v, received %v", "Updated value", getResult.Value.AsString())
+	}
+	_ = transaction.Delete(model.NewSlice([]byte("Key")))
+	if getResult := transaction.Get(model.NewSlice([]byte("Key"))); getResult.Exists || !getResult.Deleted {
+		t.Fatalf("Expected key %v to be deleted in the transaction, but was present with value %v", "Key", getResult.Value.AsString())
+	}
+	transaction.Discard()
+
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
+	if getResult := readonlyTxn.Get(model.NewSlice([]byte("Key"))); getResult.Value.AsString() != "Value" {
+		t.Fatalf("Expected %v, received %v", "Value", getResult.Value.AsString())
+	}
+}
+
+func TestGetsTheStateAsOfTheStartOfTheTransaction(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	transaction := newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Value")))
+	_ = transaction.Commit()
+
+	transaction = newTransaction(executor)
+	defer transaction.Discard()
+
+	other := newTransaction(executor)
+	_ = other.Put(model.NewSlice([]byte("Key")), model.NewSlice([]byte("Updated value")))
+	_ = other.Commit()
+
+	if getResult := transaction.Get(model.NewSlice([]byte("Key"))); getResult.Value.AsString() != "Value" {
+		t.Fatalf("Expected %v, received %v", "Value", getResult.Value.AsString())
+	}
+}
+
+func TestFailsToCommitATransactionWhichReadAKeyWrittenByAnotherTransaction(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	transaction := newTransaction(executor)
+	_ = transaction.Put(model.NewSlice([]byte("Balance")), model.NewSlice([]byte("100")))
+	_ = transaction.Commit()
+
+	transaction = newTransaction(executor)
+	_ = transaction.Get(model.NewSlice([]byte("Balance")))
+	_ = transaction.Put(model.NewSlice([]byte("Balance")), model.NewSlice([]byte("90")))
+
+	other := newTransaction(executor)
+	_ = other.Put(model.NewSlice([]byte("Balance")), model.NewSlice([]byte("50")))
+	_ = other.Commit()
+
+	err := transaction.Commit()
+	var conflictError *ConflictError
+	if !errors.As(err, &conflictError) {
+		t.Fatalf("Expected a ConflictError while committing, received %v", err)
+	}
+	if conflictError.Key.AsString() != "Balance" {
+		t.Fatalf("Expected conflict on key %v, received %v", "Balance", conflictError.Key.AsString())
+	}
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
+	if getResult := readonlyTxn.Get(model.NewSlice([]byte("Balance"))); getResult.Value.AsString() != "50" {
+		t.Fatalf("Expected %v, received %v", "50", getResult.Value.AsString())
+	}
+}
+
+func TestCommitsATransactionWhichDidNotReadTheKeysWrittenByAnotherTransaction(t *testing.T) {
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	transaction := newTransaction(executor)
+	_ = transaction.Get(model.NewSlice([]byte("HDD")))
+	_ = transaction.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+
+	other := newTransaction(executor)
+	_ = other.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+	_ = other.Commit()
+
+	if err := transaction.Commit(); err != nil {
+		t.Fatalf("Expected no error while committing, received %v", err)
+	}
+}
+
+func TestCommitsATransactionWhichReadAKeyRewrittenWhileCollectingValueLogGarbage(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 10 * 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	hardDisk := fmt.Sprintf("Hard disk %0100d", 1)
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithValueThreshold(64)
+	workspace, _ := newWorkSpace(configuration)
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte(hardDisk)))
+	_ = workspace.put(batch)
+	workspace.wal.Close()
+
+	//a restart starts a new value log file, the file with the value of HDD is collected
+	workspaceAfterRestart, _ := newWorkSpace(configuration)
+	executor := newRequestExecutor(workspaceAfterRestart)
+	laterBatch := NewBatch()
+	laterBatch.add(model.NewSlice([]byte("SDD")), model.NewSlice([]byte(fmt.Sprintf("Solid state drive %0100d", 1))))
+	_ = <-executor.put(laterBatch)
+
+	transaction := newTransaction(executor)
+	if getResult := transaction.Get(model.NewSlice([]byte("HDD"))); getResult.Value.AsString() != hardDisk {
+		t.Fatalf("Expected %v, received %v", hardDisk, getResult.Value.AsString())
+	}
+	_ = transaction.Put(model.NewSlice([]byte("PMEM")), model.NewSlice([]byte("Persistent memory")))
+
+	if err := <-executor.collectValueLogGarbage(); err != nil {
+		t.Fatalf("Expected no error while collecting value log garbage, received %v", err)
+	}
+	if getResult := workspaceAfterRestart.getUnresolved(model.NewSlice([]byte("HDD")), model.LatestSequence); getResult.Sequence <= transaction.snapshot.Sequence() {
+		t.Fatalf("Expected the value of %v to be rewritten after the start of the transaction", "HDD")
+	}
+	if err := transaction.Commit(); err != nil {
+		t.Fatalf("Expected no error while committing a transaction which read a rewritten key, received %v", err)
+	}
+}
+
+func TestIncrementsACounterInDifferentGoroutinesRetryingOnConflicts(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(10)
+
+	executor, directory := initRequestExecutor()
+	defer os.RemoveAll(directory)
+
+	key := model.NewSlice([]byte("Counter"))
+	increment := func() error {
+		transaction := newTransaction(executor)
+		counter := 0
+		if getResult := transaction.Get(key); getResult.Exists {
+			counter, _ = strconv.Atoi(getResult.Value.AsString())
+		}
+		_ = transaction.Put(key, model.NewSlice([]byte(strconv.Itoa(counter+1))))
+		return transaction.Commit()
+	}
+	for count := 1; count <= 10; count++ {
+		go func() {
+			defer wg.Done()
+			for {
+				var conflictError *ConflictError
+				if err := increment(); !errors.As(err, &conflictError) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	readonlyTxn := newReadonlyTransaction(executor.workSpace)
+	if getResult := readonlyTxn.Get(key); getResult.Value.AsString() != "10" {
+		t.Fatalf("Expected %v, received %v", "10", getResult.Value.AsString())
+	}
+}
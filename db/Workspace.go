@@ -1,19 +1,39 @@
 package db
 
 import (
+	"errors"
+	"fmt"
+	stdlog "log"
 	"storage-engine-workshop/db/model"
 	"storage-engine-workshop/log"
 	"storage-engine-workshop/storage"
+	"storage-engine-workshop/storage/cache"
+	"storage-engine-workshop/storage/iterator"
 	"storage-engine-workshop/storage/memory"
+	"storage-engine-workshop/storage/snapshot"
 	"storage-engine-workshop/storage/sst"
+	"storage-engine-workshop/storage/vlog"
+	"sync"
+	"sync/atomic"
 )
 
+// Workspace is written by the RequestExecutor goroutine only, reads can happen from any goroutine.
+// The lock guards swapping of the active memTable, the memTables themselves support concurrent lock-free reads.
+// A full memTable is handed to the flushManager, it stays readable until its ssTable is searchable.
+// Every batch is written at the next sequence, lastSequence is the sequence of the newest batch visible to readers.
+// rewrites are the sequences of the batches which rewrite live values while collecting value log garbage,
+// they are accessed by the RequestExecutor goroutine only.
 type Workspace struct {
-	wal              *log.WAL
-	ssTables         *sst.SSTables
-	activeMemTable   *memory.MemTable
-	inactiveMemTable *memory.MemTable
-	configuration    Configuration
+	lastSequence   uint64
+	rewrites       map[uint64]bool
+	wal            *log.WAL
+	ssTables       *sst.SSTables
+	activeMemTable *memory.MemTable
+	flushManager   *storage.FlushManager
+	blockCache     *cache.BlockCache
+	snapshots      *snapshot.Snapshots
+	configuration  Configuration
+	lock           sync.RWMutex
 }
 
 func newWorkSpace(configuration Configuration) (*Workspace, error) {
@@ -21,101 +41,391 @@ func newWorkSpace(configuration Configuration) (*Workspace, error) {
 	if err != nil {
 		return nil, err
 	}
-	ssTables, err := sst.NewSSTables(configuration.directory)
+	var blockCache *cache.BlockCache
+	if configuration.blockCacheSizeBytes > 0 {
+		blockCache = cache.NewBlockCache(configuration.blockCacheSizeBytes)
+	}
+	ssTables, err := sst.NewSSTablesWith(configuration.directory, blockCache, configuration.compressionCodec, configuration.keyComparator)
 	if err != nil {
 		return nil, err
 	}
-	return &Workspace{
+	workspace := &Workspace{
 		wal:            wal,
 		ssTables:       ssTables,
+		blockCache:     blockCache,
 		activeMemTable: memory.NewMemTable(32, configuration.keyComparator),
+		snapshots:      snapshot.NewSnapshots(),
 		configuration:  configuration,
-	}, nil
+		lastSequence:   ssTables.PersistedSequence(),
+		rewrites:       make(map[uint64]bool),
+	}
+	workspace.flushManager = storage.NewFlushManager(ssTables, configuration.maxImmutableMemTables, workspace.checkpointWAL)
+	if err := workspace.replayWAL(); err != nil {
+		return nil, err
+	}
+	workspace.checkpointWAL(ssTables.PersistedWALOffset())
+	ssTables.StartCompaction(configuration.compactionOptions, configuration.keyComparator, workspace.snapshots)
+	return workspace, nil
 }
 
-func (workspace *Workspace) put(batch *Batch) error {
-	writeToSSTable := func() {
-		//handle error
-		storage.NewMemTableWriter(workspace.activeMemTable, workspace.ssTables).Write()
+// replayWAL replays the transactions which are not yet persisted in ssTables, as recorded in the manifest.
+// Transactions written before sequences were introduced get the next sequence in the order of the WAL
+func (workspace *Workspace) replayWAL() error {
+	transactionalEntries, err := workspace.wal.ReadAllFrom(workspace.ssTables.PersistedWALOffset())
+	if err != nil {
+		return err
+	}
+	for _, transactionalEntry := range transactionalEntries {
+		sequence := transactionalEntry.Sequence()
+		if sequence == 0 {
+			sequence = workspace.lastSequence + 1
+		}
+		if sequence > workspace.lastSequence {
+			workspace.lastSequence = sequence
+		}
+		if !transactionalEntry.IsSuccess() {
+			continue
+		}
+		for _, keyValuePair := range transactionalEntry.AllKeyValuePairs() {
+			workspace.activeMemTable.PutKeyValuePair(model.KeyValuePair{
+				Key:          keyValuePair.Key.GetSlice(),
+				Value:        keyValuePair.Value.GetSlice(),
+				Deleted:      keyValuePair.Deleted,
+				ValuePointer: keyValuePair.ValuePointer,
+				Sequence:     sequence,
+			})
+		}
+	}
+	return nil
+}
+
+// checkpointWAL deletes the WAL segments whose contents are persisted in ssTables
+func (workspace *Workspace) checkpointWAL(offset int64) {
+	if err := workspace.wal.Checkpoint(offset); err != nil {
+		stdlog.Default().Println("Error while checkpointing the WAL " + err.Error())
 	}
-	mayBeSwapMemTable := func() {
+}
+
+func (workspace *Workspace) put(batch *Batch) error {
+	return workspace.putAll([]*Batch{batch})[0]
+}
+
+// putAll writes the batches to the WAL as one transaction each with a single write, syncs the WAL once if the sync policy
+// needs it and then applies the batches to the memTable in order. A batch is visible to readers only after it is durable as per the sync policy.
+// The workspace becomes read-only once a memTable fails to flush, all the later batches fail with the error of the flush.
+func (workspace *Workspace) putAll(batches []*Batch) []error {
+	mayBeSwapMemTable := func() error {
 		if workspace.activeMemTable.TotalSize() >= workspace.configuration.bufferSizeBytes {
-			writeToSSTable()
-			workspace.inactiveMemTable = workspace.activeMemTable
+			//the values which the flushed ssTable points to must be durable before the WAL is checkpointed
+			if err := workspace.valueLog().Sync(); err != nil {
+				return err
+			}
+			//the active memTable is swapped between transactions, it contains the WAL contents up to the last offset.
+			//Schedule stalls the writes while the maximum number of immutable memTables are waiting to be flushed
+			if err := workspace.flushManager.Schedule(workspace.activeMemTable, workspace.wal.LastOffset()); err != nil {
+				return readOnlyError(err)
+			}
+			workspace.lock.Lock()
 			workspace.activeMemTable = memory.NewMemTable(32, workspace.configuration.keyComparator)
+			workspace.lock.Unlock()
 		}
+		return nil
 	}
-	putInMemTable := func() {
+	putInMemTable := func(batch *Batch, sequence uint64) {
 		for _, keyValuePair := range batch.keyValuePairs {
-			mayBeSwapMemTable()
-			workspace.activeMemTable.Put(keyValuePair.Key, keyValuePair.Value)
+			keyValuePair.Sequence = sequence
+			workspace.activeMemTable.PutKeyValuePair(keyValuePair)
+		}
+	}
+	//separateValues writes the values larger than the threshold to the value log and replaces them with their pointers in the batches
+	separateValues := func() (bool, error) {
+		thresholdBytes, separated := workspace.configuration.valueThresholdBytes, false
+		if thresholdBytes <= 0 {
+			return false, nil
+		}
+		for index, batch := range batches {
+			if !batch.hasValueLargerThan(thresholdBytes) {
+				continue
+			}
+			separatedBatch := NewBatch()
+			for _, keyValuePair := range batch.keyValuePairs {
+				if keyValuePair.Deleted || keyValuePair.Value.Size() <= thresholdBytes {
+					separatedBatch.append(keyValuePair)
+					continue
+				}
+				valuePointer, err := workspace.valueLog().Append(keyValuePair.Key, keyValuePair.Value)
+				if err != nil {
+					return false, err
+				}
+				separatedBatch.append(model.KeyValuePair{Key: keyValuePair.Key, Value: valuePointer.Encode(), ValuePointer: true})
+			}
+			batches[index], separated = separatedBatch, true
 		}
+		return separated, nil
 	}
+	//the batches are written at consecutive sequences, starting after the sequence of the newest batch
+	firstSequence := atomic.LoadUint64(&workspace.lastSequence) + 1
 	write := func() error {
-		//Assignment:Transaction:4:write the transaction header
-		var err error = nil
-		if err != nil {
-			return err
+		allEntries := make([]log.PersistentLogSlice, len(batches))
+		for index, batch := range batches {
+			allEntries[index] = batch.allEntriesAsPersistentLogSlice()
 		}
-		//Assignment:Transaction:5:write the transaction data
-		if err != nil {
-			return err
+		return workspace.wal.AppendTransactions(allEntries, firstSequence)
+	}
+	errs := make([]error, len(batches))
+	fail := func(err error) {
+		for index := range errs {
+			errs[index] = err
 		}
-		putInMemTable()
-		//Assignment:Transaction:5:write the transaction footer
-		return workspace.wal.MarkTransactionWith(log.TransactionStatusSuccess())
 	}
-	return write()
+
+	if err := workspace.flushManager.Err(); err != nil {
+		fail(readOnlyError(err))
+		return errs
+	}
+	//the memTable is swapped only when all the batches written to the WAL are applied to it
+	if err := mayBeSwapMemTable(); err != nil {
+		fail(err)
+		return errs
+	}
+	separated, err := separateValues()
+	if err != nil {
+		fail(err)
+		return errs
+	}
+	if err := write(); err != nil {
+		fail(err)
+		return errs
+	}
+	if workspace.configuration.syncPolicy.syncsOnCommit() {
+		if separated {
+			if err := workspace.valueLog().Sync(); err != nil {
+				fail(err)
+				return errs
+			}
+		}
+		if err := workspace.wal.Sync(); err != nil {
+			fail(err)
+			return errs
+		}
+	}
+	for index, batch := range batches {
+		putInMemTable(batch, firstSequence+uint64(index))
+	}
+	atomic.StoreUint64(&workspace.lastSequence, firstSequence+uint64(len(batches))-1)
+	return errs
 }
 
-func (workspace *Workspace) get(key model.Slice) model.GetResult {
-	memTables := []*memory.MemTable{workspace.activeMemTable, workspace.inactiveMemTable}
-	//get := func(memTable *memory.MemTable) model.GetResult {
-	//	return memTable.Get(key)
-	//}
-	for _, memTable := range memTables {
-		if memTable != nil {
-			//Assignment:Transaction:6:get from memtables by using `get`
-			var getResult model.GetResult
-			if getResult.Exists {
-				return getResult
+// checkConflicts returns a ConflictError for the first key in the readSet which is written after the startSequence,
+// or is written by one of the pendingBatches which are yet to be put. It runs in the RequestExecutor goroutine
+func (workspace *Workspace) checkConflicts(readSet []model.Slice, startSequence uint64, pendingBatches []*Batch) error {
+	for _, key := range readSet {
+		for _, batch := range pendingBatches {
+			if _, ok := batch.get(key, workspace.configuration.keyComparator); ok {
+				return &ConflictError{Key: key}
 			}
 		}
+		//a value rewritten while collecting value log garbage is not a write of the key, the version before the rewrite is checked instead
+		getResult := workspace.getUnresolved(key, model.LatestSequence)
+		for getResult.Err == nil && getResult.Sequence > startSequence && workspace.rewrites[getResult.Sequence] {
+			getResult = workspace.getUnresolved(key, getResult.Sequence-1)
+		}
+		if getResult.Err != nil {
+			return getResult.Err
+		}
+		if getResult.Sequence > startSequence {
+			return &ConflictError{Key: key}
+		}
 	}
+	return nil
+}
 
-	//Assignment:Transaction:7:get from sstables
-	return model.GetResult{}
+// syncWAL syncs the value log before the WAL, the WAL may contain pointers to the values in the value log
+func (workspace *Workspace) syncWAL() error {
+	if err := workspace.valueLog().Sync(); err != nil {
+		return err
+	}
+	return workspace.wal.Sync()
 }
 
-func (workspace *Workspace) multiGet(keys []model.Slice) []model.GetResult {
-	index, allGetResults := 0, make([]model.GetResult, len(keys))
+// close syncs the WAL, waits for the pending flushes, stops the compaction and closes all the files, it returns the first error.
+// The unflushed memTables are recovered from the WAL by the next newWorkSpace
+func (workspace *Workspace) close() error {
+	err := workspace.syncWAL()
+	keepFirst := func(closeErr error) {
+		if err == nil {
+			err = closeErr
+		}
+	}
+	keepFirst(workspace.flushManager.WaitForPendingFlushes())
+	keepFirst(workspace.ssTables.Close())
+	keepFirst(workspace.wal.Close())
+	return err
+}
 
-	buildResult := func(multiGetResult model.MultiGetResult) {
-		for _, getResult := range multiGetResult.Values {
-			if getResult.Exists {
-				allGetResults[index] = getResult
-				index = index + 1
+// collectValueLogGarbage rewrites the live values of the oldest value log file, the file is deleted once no snapshot older than the rewrite is alive.
+// It runs in the RequestExecutor goroutine, so no write can change a key between checking its liveness and rewriting it
+func (workspace *Workspace) collectValueLogGarbage() error {
+	isLive := func(key model.Slice, valuePointer vlog.ValuePointer) bool {
+		getResult := workspace.getUnresolved(key, model.LatestSequence)
+		//a value whose liveness can not be determined is kept
+		if getResult.Err != nil {
+			return true
+		}
+		if !getResult.Exists || !getResult.ValuePointer {
+			return false
+		}
+		current, err := vlog.DecodeValuePointer(getResult.Value)
+		return err == nil && current == valuePointer
+	}
+	//readers at the sequence after the rewrite see the rewritten values, older readers may still see the values in the collected file
+	rewrite := func(keyValuePairs []model.KeyValuePair) (uint64, error) {
+		if len(keyValuePairs) > 0 {
+			batch := NewBatch()
+			for _, keyValuePair := range keyValuePairs {
+				batch.add(keyValuePair.Key, keyValuePair.Value)
+			}
+			if err := workspace.put(batch); err != nil {
+				return 0, err
+			}
+			if err := workspace.syncWAL(); err != nil {
+				return 0, err
+			}
+			workspace.rewrites[atomic.LoadUint64(&workspace.lastSequence)] = true
+		}
+		return atomic.LoadUint64(&workspace.lastSequence), nil
+	}
+	//a rewrite at or before the oldest snapshot is older than the start of every transaction which can still commit
+	forgetOldRewrites := func() {
+		oldestSequence := workspace.snapshots.OldestSequence()
+		for sequence := range workspace.rewrites {
+			if sequence <= oldestSequence {
+				delete(workspace.rewrites, sequence)
 			}
 		}
 	}
-	multiGetIn := func(memTable *memory.MemTable, keys []model.Slice) []model.Slice {
+	forgetOldRewrites()
+	_, err := workspace.valueLog().CollectGarbage(isLive, rewrite, workspace.snapshots.OldestSequence)
+	return err
+}
+
+// get returns the newest version of the key with a sequence less than or equal to the sequence.
+// The result carries the error which prevented reading the key, Exists alone does not tell a missing key from a failed read
+func (workspace *Workspace) get(key model.Slice, sequence uint64) model.GetResult {
+	return workspace.resolve(workspace.getUnresolved(key, sequence))
+}
+
+// getUnresolved returns the encoded value pointer instead of the value for a key whose value is in the value log
+func (workspace *Workspace) getUnresolved(key model.Slice, sequence uint64) model.GetResult {
+	memTables := workspace.memTables()
+	get := func(memTable *memory.MemTable) model.GetResult {
+		return memTable.GetAt(key, sequence)
+	}
+	for _, memTable := range memTables {
 		if memTable != nil {
-			multiGetResult, missingKeys := workspace.activeMemTable.MultiGet(keys)
-			buildResult(multiGetResult)
-			return missingKeys
+			if getResult := get(memTable); getResult.Exists || getResult.Deleted {
+				return getResult
+			}
 		}
-		return []model.Slice{}
 	}
+	return workspace.ssTables.GetUnresolved(key, sequence, workspace.configuration.keyComparator)
+}
 
-	missingKeys := multiGetIn(workspace.activeMemTable, keys)
-	missingKeys = multiGetIn(workspace.inactiveMemTable, missingKeys)
+func (workspace *Workspace) resolve(getResult model.GetResult) model.GetResult {
+	if !getResult.ValuePointer {
+		return getResult
+	}
+	value, err := workspace.valueLog().Resolve(getResult.Value)
+	if err != nil {
+		return model.GetResult{Key: getResult.Key, Exists: false, Err: err}
+	}
+	return model.GetResult{Key: getResult.Key, Value: value, Exists: true, Sequence: getResult.Sequence}
+}
 
+func (workspace *Workspace) newIterator(sequence uint64) (iterator.Iterator, error) {
+	var iterators []iterator.Iterator
+	for _, memTable := range workspace.memTables() {
+		if memTable != nil {
+			iterators = append(iterators, memTable.NewIteratorAt(sequence))
+		}
+	}
+	ssTableIterators, err := workspace.ssTables.NewIterators(sequence, workspace.configuration.keyComparator)
+	if err != nil {
+		return nil, err
+	}
+	iterators = append(iterators, ssTableIterators...)
+	return vlog.NewResolvingIterator(iterator.NewMergedIterator(iterators, workspace.configuration.keyComparator), workspace.valueLog()), nil
+}
+
+// multiGet returns the results positionally aligned with the keys. Each memTable is searched once for the keys not found
+// in the newer memTables, the keys found in none of them are searched in ssTables.
+// The result of a key carries the error which prevented reading it
+func (workspace *Workspace) multiGet(keys []model.Slice, sequence uint64) []model.GetResult {
+	allGetResults := make([]model.GetResult, len(keys))
+	positionsByKey := make(map[string][]int, len(keys))
+	var missingKeys []model.Slice
+	for index, key := range keys {
+		if _, ok := positionsByKey[key.AsString()]; !ok {
+			missingKeys = append(missingKeys, key)
+		}
+		positionsByKey[key.AsString()] = append(positionsByKey[key.AsString()], index)
+	}
+	setResult := func(getResult model.GetResult) {
+		for _, position := range positionsByKey[getResult.Key.AsString()] {
+			allGetResults[position] = getResult
+		}
+	}
+
+	for _, memTable := range workspace.memTables() {
+		if len(missingKeys) == 0 {
+			break
+		}
+		multiGetResult, keysNotInMemTable := memTable.MultiGetAt(missingKeys, sequence)
+		for _, getResult := range multiGetResult.Values {
+			setResult(workspace.resolve(getResult))
+		}
+		missingKeys = keysNotInMemTable
+	}
 	if len(missingKeys) > 0 {
-		getResults := workspace.ssTables.MultiGet(missingKeys, workspace.configuration.keyComparator).Values
-		for _, getResult := range getResults {
-			allGetResults[index] = getResult
-			index = index + 1
+		for _, getResult := range workspace.ssTables.MultiGetAt(missingKeys, sequence, workspace.configuration.keyComparator).Values {
+			setResult(getResult)
 		}
 	}
 	return allGetResults
 }
+
+// newSnapshot takes a snapshot at the sequence of the newest batch visible to readers
+func (workspace *Workspace) newSnapshot() *snapshot.Snapshot {
+	return workspace.snapshots.Take(func() uint64 {
+		return atomic.LoadUint64(&workspace.lastSequence)
+	})
+}
+
+func (workspace *Workspace) verify() ([]*sst.CorruptionError, error) {
+	return workspace.ssTables.Scrub()
+}
+
+func (workspace *Workspace) blockCacheStats() cache.Stats {
+	if workspace.blockCache == nil {
+		return cache.Stats{}
+	}
+	return workspace.blockCache.Stats()
+}
+
+func (workspace *Workspace) valueLog() *vlog.ValueLog {
+	return workspace.ssTables.ValueLog()
+}
+
+// memTables returns the active memTable followed by the immutable memTables, newest first.
+// A memTable is scheduled to be flushed before it is swapped out, so it is always part of one of them
+func (workspace *Workspace) memTables() []*memory.MemTable {
+	workspace.lock.RLock()
+	activeMemTable := workspace.activeMemTable
+	workspace.lock.RUnlock()
+
+	return append([]*memory.MemTable{activeMemTable}, workspace.flushManager.MemTables()...)
+}
+
+func readOnlyError(flushErr error) error {
+	return errors.New(fmt.Sprintf("workspace is read-only after a failed memTable flush: %v", flushErr.Error()))
+}
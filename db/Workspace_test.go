@@ -1,9 +1,13 @@
 package db
 
 import (
+	"fmt"
 	"os"
+	"path"
 	"storage-engine-workshop/db/model"
+	wal "storage-engine-workshop/log"
 	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/memory"
 	"strconv"
 	"testing"
 )
@@ -34,7 +38,7 @@ func TestPut200KeysValuesAndGetByKeysInWorkspace(t *testing.T) {
 	allowFlushingSSTable()
 
 	for count := 1; count <= 200; count++ {
-		getResult := workspace.get(keyUsing(count))
+		getResult := workspace.get(keyUsing(count), model.LatestSequence)
 		expectedValue := valueUsing(count)
 
 		if getResult.Value.AsString() != expectedValue.AsString() {
@@ -61,7 +65,7 @@ func TestPut1000KeysValuesAndMultiGetKeysInWorkspace(t *testing.T) {
 	workspace, _ := newWorkSpace(configuration)
 
 	batch := NewBatch()
-	for count := 1; count <= 200; count++ {
+	for count := 1; count <= 1000; count++ {
 		batch.add(keyUsing(count), valueUsing(count))
 	}
 	_ = workspace.put(batch)
@@ -83,10 +87,572 @@ func TestPut1000KeysValuesAndMultiGetKeysInWorkspace(t *testing.T) {
 		"Key-900":     "Value-900",
 		"Key-Unknown": "",
 	}
-	multiGetResult := workspace.multiGet(keys)
-	for _, result := range multiGetResult {
+	multiGetResult := workspace.multiGet(keys, model.LatestSequence)
+	for index, result := range multiGetResult {
+		if result.Key.AsString() != keys[index].AsString() {
+			t.Fatalf("Expected result of key %v at position %v, received %v", keys[index].AsString(), index, result.Key.AsString())
+		}
 		if result.Value.AsString() != expectedValueByKey[result.Key.AsString()] {
 			t.Fatalf("Expected value to be %v, received %v", expectedValueByKey[result.Key.AsString()], result.Value.AsString())
 		}
 	}
 }
+
+func TestReplaysSuccessfulTransactionsFromWALOnRestartOfWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 32
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	batch.add(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+	_ = workspace.put(batch)
+	workspace.wal.Close()
+
+	workspaceAfterRestart, err := newWorkSpace(configuration)
+	if err != nil {
+		t.Fatalf("Expected no error while restarting the workspace but received %v", err)
+	}
+	expectedValueByKey := map[string]string{
+		"HDD": "Hard disk",
+		"SDD": "Solid state",
+	}
+	for key, expectedValue := range expectedValueByKey {
+		getResult := workspaceAfterRestart.get(model.NewSlice([]byte(key)), model.LatestSequence)
+		if getResult.Value.AsString() != expectedValue {
+			t.Fatalf("Expected %v, received %v", expectedValue, getResult.Value.AsString())
+		}
+	}
+}
+
+func TestDoesNotReplayFailedTransactionsFromWALOnRestartOfWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 32
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	_ = workspace.wal.BeginTransactionHeader(batch.totalSize(), 1)
+	_ = workspace.wal.Append(batch.allEntriesAsPersistentLogSlice())
+	_ = workspace.wal.MarkTransactionWith(wal.TransactionStatusFailed())
+	workspace.wal.Close()
+
+	workspaceAfterRestart, err := newWorkSpace(configuration)
+	if err != nil {
+		t.Fatalf("Expected no error while restarting the workspace but received %v", err)
+	}
+	if getResult := workspaceAfterRestart.get(model.NewSlice([]byte("HDD")), model.LatestSequence); getResult.Exists {
+		t.Fatalf("Expected key %v to be missing after replaying a failed transaction, but was present", "HDD")
+	}
+}
+
+func TestDeletesKeysFlushedToSSTablesInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 512
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	keyUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Key-" + strconv.Itoa(count)))
+	}
+	valueUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Value-" + strconv.Itoa(count)))
+	}
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	for count := 1; count <= 200; count++ {
+		batch.add(keyUsing(count), valueUsing(count))
+	}
+	_ = workspace.put(batch)
+
+	deleteBatch := NewBatch()
+	deleteBatch.delete(keyUsing(1))
+	deleteBatch.delete(keyUsing(150))
+	_ = workspace.put(deleteBatch)
+
+	allowFlushingSSTable()
+
+	for _, count := range []int{1, 150} {
+		if getResult := workspace.get(keyUsing(count), model.LatestSequence); getResult.Exists {
+			t.Fatalf("Expected key %v to be deleted, but was present", keyUsing(count).AsString())
+		}
+	}
+	if getResult := workspace.get(keyUsing(2), model.LatestSequence); getResult.Value.AsString() != valueUsing(2).AsString() {
+		t.Fatalf("Expected %v, received %v", valueUsing(2).AsString(), getResult.Value.AsString())
+	}
+}
+
+func TestReplaysTombstonesFromWALOnRestartOfWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 32
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	_ = workspace.put(batch)
+
+	deleteBatch := NewBatch()
+	deleteBatch.delete(model.NewSlice([]byte("HDD")))
+	_ = workspace.put(deleteBatch)
+	workspace.wal.Close()
+
+	workspaceAfterRestart, _ := newWorkSpace(configuration)
+	if getResult := workspaceAfterRestart.get(model.NewSlice([]byte("HDD")), model.LatestSequence); getResult.Exists {
+		t.Fatalf("Expected key %v to be deleted after restart, but was present", "HDD")
+	}
+}
+
+func TestIteratesOverKeysInMemTablesAndSSTablesInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 512
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	keyUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Key-" + fmt.Sprintf("%03d", count)))
+	}
+	valueUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Value-" + strconv.Itoa(count)))
+	}
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	for count := 1; count <= 200; count++ {
+		batch.add(keyUsing(count), valueUsing(count))
+	}
+	_ = workspace.put(batch)
+
+	allowFlushingSSTable()
+
+	iterator, err := workspace.newIterator(model.LatestSequence)
+	if err != nil {
+		t.Fatalf("Expected no error while creating an iterator but received %v", err)
+	}
+	iterator.Seek(model.NilSlice())
+
+	for count := 1; count <= 200; count++ {
+		if iterator.Key().AsString() != keyUsing(count).AsString() {
+			t.Fatalf("Expected key to be %v, received %v", keyUsing(count).AsString(), iterator.Key().AsString())
+		}
+		if iterator.Value().AsString() != valueUsing(count).AsString() {
+			t.Fatalf("Expected %v, received %v", valueUsing(count).AsString(), iterator.Value().AsString())
+		}
+		iterator.Next()
+	}
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
+
+func TestReplaysOnlyTransactionsNotPersistedInSSTablesOnRestartOfWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 512
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	keyUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Key-" + strconv.Itoa(count)))
+	}
+	valueUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Value-" + strconv.Itoa(count)))
+	}
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	for count := 1; count <= 100; count++ {
+		batch.add(keyUsing(count), valueUsing(count))
+	}
+	_ = workspace.put(batch)
+
+	laterBatch := NewBatch()
+	laterBatch.add(keyUsing(101), valueUsing(101))
+	_ = workspace.put(laterBatch)
+
+	if err := workspace.flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected memTable to be flushed without an error, received %v", err)
+	}
+	workspace.wal.Close()
+
+	workspaceAfterRestart, err := newWorkSpace(configuration)
+	if err != nil {
+		t.Fatalf("Expected no error while restarting the workspace but received %v", err)
+	}
+	if totalKeys := workspaceAfterRestart.activeMemTable.TotalKeys(); totalKeys != 1 {
+		t.Fatalf("Expected only the key persisted after the flush to be replayed, received %v keys", totalKeys)
+	}
+	for count := 1; count <= 101; count++ {
+		if getResult := workspaceAfterRestart.get(keyUsing(count), model.LatestSequence); getResult.Value.AsString() != valueUsing(count).AsString() {
+			t.Fatalf("Expected %v, received %v", valueUsing(count).AsString(), getResult.Value.AsString())
+		}
+	}
+}
+
+func TestDeletesWALSegmentsPersistedInSSTablesInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 64
+	const bufferMaxSizeBytes uint64 = 512
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	for count := 1; count <= 40; count++ {
+		batch := NewBatch()
+		batch.add(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		_ = workspace.put(batch)
+	}
+	if err := workspace.flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected memTable to be flushed without an error, received %v", err)
+	}
+
+	transactionalEntries, _ := workspace.wal.ReadAll()
+	if len(transactionalEntries) >= 40 {
+		t.Fatalf("Expected WAL segments persisted in ssTables to be deleted, received %v transactions", len(transactionalEntries))
+	}
+	for count := 1; count <= 40; count++ {
+		key := model.NewSlice([]byte("Key-" + strconv.Itoa(count)))
+		if getResult := workspace.get(key, model.LatestSequence); getResult.Value.AsString() != "Value-"+strconv.Itoa(count) {
+			t.Fatalf("Expected %v, received %v", "Value-"+strconv.Itoa(count), getResult.Value.AsString())
+		}
+	}
+}
+
+func TestPutsMultipleBatchesInOrderInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	laterBatch := NewBatch()
+	laterBatch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk drive")))
+
+	for _, err := range workspace.putAll([]*Batch{batch, laterBatch}) {
+		if err != nil {
+			t.Fatalf("Expected no error while putting batches, received %v", err)
+		}
+	}
+	if getResult := workspace.get(model.NewSlice([]byte("HDD")), model.LatestSequence); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+	transactionalEntries, _ := workspace.wal.ReadAll()
+	if len(transactionalEntries) != 2 {
+		t.Fatalf("Expected %v transactional entries in the WAL, received %v", 2, len(transactionalEntries))
+	}
+}
+
+func TestKeepsValuesLargerThanTheThresholdInTheValueLogInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 512
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	largeValueUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte(fmt.Sprintf("Large-Value-%v-%0100d", count, count)))
+	}
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithValueThreshold(64)
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	batch.add(model.NewSlice([]byte("Key-0")), largeValueUsing(0))
+	_ = workspace.put(batch)
+
+	if getResult := workspace.activeMemTable.Get(model.NewSlice([]byte("Key-0"))); !getResult.ValuePointer {
+		t.Fatalf("Expected the memTable to hold a value pointer for a value larger than the threshold")
+	}
+	if getResult := workspace.activeMemTable.Get(model.NewSlice([]byte("HDD"))); getResult.ValuePointer {
+		t.Fatalf("Expected the memTable to hold the value for a value smaller than the threshold")
+	}
+	for count := 1; count <= 40; count++ {
+		batch := NewBatch()
+		batch.add(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), largeValueUsing(count))
+		_ = workspace.put(batch)
+	}
+	if err := workspace.flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected memTable to be flushed without an error, received %v", err)
+	}
+	workspace.wal.Close()
+
+	workspaceAfterRestart, _ := newWorkSpace(configuration)
+	for count := 0; count <= 40; count++ {
+		if getResult := workspaceAfterRestart.get(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.LatestSequence); getResult.Value.AsString() != largeValueUsing(count).AsString() {
+			t.Fatalf("Expected %v, received %v", largeValueUsing(count).AsString(), getResult.Value.AsString())
+		}
+	}
+	iterator, _ := workspaceAfterRestart.newIterator(model.LatestSequence)
+	defer iterator.Close()
+
+	iterator.Seek(model.NewSlice([]byte("Key-0")))
+	if iterator.Value().AsString() != largeValueUsing(0).AsString() {
+		t.Fatalf("Expected %v, received %v", largeValueUsing(0).AsString(), iterator.Value().AsString())
+	}
+}
+
+func TestCollectsValueLogGarbageInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 10 * 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	hardDisk, solidStateDrive := fmt.Sprintf("Hard disk %0100d", 1), fmt.Sprintf("Solid state drive %0100d", 1)
+	hardDiskDrive := fmt.Sprintf("Hard disk drive %0100d", 1)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithValueThreshold(64)
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte(hardDisk)))
+	batch.add(model.NewSlice([]byte("SDD")), model.NewSlice([]byte(solidStateDrive)))
+	_ = workspace.put(batch)
+	workspace.wal.Close()
+
+	//a restart starts a new value log file, the file with the older values can be collected
+	workspaceAfterRestart, _ := newWorkSpace(configuration)
+	laterBatch := NewBatch()
+	laterBatch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte(hardDiskDrive)))
+	_ = workspaceAfterRestart.put(laterBatch)
+
+	if err := workspaceAfterRestart.collectValueLogGarbage(); err != nil {
+		t.Fatalf("Expected no error while collecting value log garbage, received %v", err)
+	}
+	if _, err := os.Stat(path.Join(directory, "vlog", "1.vlog")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the oldest value log file to be deleted after collecting garbage")
+	}
+	expected := map[string]string{"HDD": hardDiskDrive, "SDD": solidStateDrive}
+	for key, value := range expected {
+		if getResult := workspaceAfterRestart.get(model.NewSlice([]byte(key)), model.LatestSequence); getResult.Value.AsString() != value {
+			t.Fatalf("Expected %v, received %v", value, getResult.Value.AsString())
+		}
+	}
+}
+
+func TestRejectsWritesAfterAFailedMemTableFlushInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	_ = workspace.put(batch)
+
+	//an empty memTable fails to flush
+	_ = workspace.flushManager.Schedule(memory.NewMemTable(10, comparator.StringKeyComparator{}), 0)
+	if err := workspace.flushManager.WaitForPendingFlushes(); err == nil {
+		t.Fatalf("Expected an error while flushing an empty memTable, received none")
+	}
+
+	laterBatch := NewBatch()
+	laterBatch.add(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+	if err := workspace.put(laterBatch); err == nil {
+		t.Fatalf("Expected an error while writing to a read-only workspace, received none")
+	}
+	if getResult := workspace.get(model.NewSlice([]byte("HDD")), model.LatestSequence); getResult.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", getResult.Value.AsString())
+	}
+	if getResult := workspace.get(model.NewSlice([]byte("SDD")), model.LatestSequence); getResult.Exists {
+		t.Fatalf("Expected key %v to be missing, but was present", "SDD")
+	}
+}
+
+func TestRejectsABatchWhenTheValueLogFailsToSyncBeforeSwappingTheMemTableInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 10 * 1024
+	const bufferMaxSizeBytes uint64 = 256
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).WithValueThreshold(64)
+	workspace, _ := newWorkSpace(configuration)
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("HDD")), model.NewSlice([]byte(fmt.Sprintf("Hard disk %0300d", 1))))
+	for count := 1; count <= 30; count++ {
+		batch.add(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+	}
+	if err := workspace.put(batch); err != nil {
+		t.Fatalf("Expected no error while putting a batch, received %v", err)
+	}
+	//a closed value log fails to sync before the full memTable is swapped
+	activeMemTable := workspace.activeMemTable
+	workspace.valueLog().Close()
+
+	laterBatch := NewBatch()
+	laterBatch.add(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+	if err := workspace.put(laterBatch); err == nil {
+		t.Fatalf("Expected an error while putting a batch with a value log failing to sync, received none")
+	}
+	if workspace.activeMemTable != activeMemTable {
+		t.Fatalf("Expected the active memTable not to be swapped after the value log failed to sync")
+	}
+	if getResult := workspace.get(model.NewSlice([]byte("SDD")), model.LatestSequence); getResult.Exists {
+		t.Fatalf("Expected key %v to be missing, but was present", "SDD")
+	}
+}
+
+func TestKeepsImmutableMemTablesReadableWhileWritesStallInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 64
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).
+		WithMaxImmutableMemTables(1)
+	workspace, _ := newWorkSpace(configuration)
+
+	for count := 1; count <= 50; count++ {
+		batch := NewBatch()
+		batch.add(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value-"+strconv.Itoa(count))))
+		_ = workspace.put(batch)
+
+		if pending := len(workspace.flushManager.MemTables()); pending > 1 {
+			t.Fatalf("Expected at most %v immutable memTable, received %v", 1, pending)
+		}
+		for readCount := 1; readCount <= count; readCount++ {
+			if getResult := workspace.get(model.NewSlice([]byte("Key-"+strconv.Itoa(readCount))), model.LatestSequence); getResult.Value.AsString() != "Value-"+strconv.Itoa(readCount) {
+				t.Fatalf("Expected %v, received %v", "Value-"+strconv.Itoa(readCount), getResult.Value.AsString())
+			}
+		}
+	}
+	if err := workspace.flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected memTables to be flushed without an error, received %v", err)
+	}
+}
+
+func TestGetsMultipleKeysAndIteratesAcrossImmutableMemTablesInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 32
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{}).
+		WithMaxImmutableMemTables(4)
+	workspace, _ := newWorkSpace(configuration)
+
+	keyUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte(fmt.Sprintf("Key-%02d", count)))
+	}
+	valueUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte(fmt.Sprintf("Value-%02d", count)))
+	}
+	for count := 1; count <= 30; count++ {
+		batch := NewBatch()
+		batch.add(keyUsing(count), valueUsing(count))
+		_ = workspace.put(batch)
+
+		values := map[string]bool{}
+		for _, getResult := range workspace.multiGet([]model.Slice{keyUsing(1), keyUsing(count)}, model.LatestSequence) {
+			values[getResult.Value.AsString()] = getResult.Exists
+		}
+		if !values[valueUsing(1).AsString()] || !values[valueUsing(count).AsString()] {
+			t.Fatalf("Expected %v and %v to be found, received %v", valueUsing(1).AsString(), valueUsing(count).AsString(), values)
+		}
+
+		iterator, _ := workspace.newIterator(model.LatestSequence)
+		iteratedKeys := 0
+		for iterator.Seek(model.NilSlice()); iterator.IsValid(); iterator.Next() {
+			iteratedKeys = iteratedKeys + 1
+		}
+		iterator.Close()
+		if iteratedKeys != count {
+			t.Fatalf("Expected %v keys to be iterated, received %v", count, iteratedKeys)
+		}
+	}
+	if err := workspace.flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected memTables to be flushed without an error, received %v", err)
+	}
+}
+
+func TestMultiGetsKeysWithResultsAlignedToTheKeysInWorkspace(t *testing.T) {
+	const segmentMaxSizeBytes uint64 = 1024
+	const bufferMaxSizeBytes uint64 = 1024
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	configuration := NewConfiguration(directory, segmentMaxSizeBytes, bufferMaxSizeBytes, comparator.StringKeyComparator{})
+	workspace, _ := newWorkSpace(configuration)
+
+	flushed := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	flushed.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk")), Sequence: 1})
+	flushed.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("SDD")), Value: model.NewSlice([]byte("Solid state")), Sequence: 1})
+	_ = workspace.flushManager.Schedule(flushed, 0)
+	if err := workspace.flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected memTable to be flushed without an error, received %v", err)
+	}
+
+	batch := NewBatch()
+	batch.add(model.NewSlice([]byte("PMEM")), model.NewSlice([]byte("Persistent memory")))
+	batch.delete(model.NewSlice([]byte("SDD")))
+	_ = workspace.put(batch)
+	workspace.activeMemTable.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("Tape")), Value: model.NewSlice([]byte("not a pointer")), ValuePointer: true, Sequence: 3})
+
+	keys := []model.Slice{
+		model.NewSlice([]byte("SDD")),
+		model.NewSlice([]byte("PMEM")),
+		model.NewSlice([]byte("Unknown")),
+		model.NewSlice([]byte("HDD")),
+		model.NewSlice([]byte("Tape")),
+		model.NewSlice([]byte("PMEM")),
+	}
+	expectedValues := []string{"", "Persistent memory", "", "Hard disk", "", "Persistent memory"}
+	multiGetResult := workspace.multiGet(keys, model.LatestSequence)
+
+	if len(multiGetResult) != len(keys) {
+		t.Fatalf("Expected %v results, received %v", len(keys), len(multiGetResult))
+	}
+	for index, getResult := range multiGetResult {
+		if getResult.Key.AsString() != keys[index].AsString() {
+			t.Fatalf("Expected result of key %v at position %v, received %v", keys[index].AsString(), index, getResult.Key.AsString())
+		}
+		if getResult.Value.AsString() != expectedValues[index] {
+			t.Fatalf("Expected %v, received %v", expectedValues[index], getResult.Value.AsString())
+		}
+	}
+	if !multiGetResult[0].Deleted || multiGetResult[0].Exists {
+		t.Fatalf("Expected key %v to be deleted, but was present", "SDD")
+	}
+	if multiGetResult[4].Err == nil || multiGetResult[4].Exists {
+		t.Fatalf("Expected an error while resolving the value pointer of key %v, received none", "Tape")
+	}
+}
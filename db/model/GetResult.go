@@ -1,8 +1,13 @@
 package model
 
+// GetResult carries the Err which prevented reading the key, Exists is false in that case
 type GetResult struct {
-	Key, Value Slice
-	Exists     bool
+	Key, Value   Slice
+	Exists       bool
+	Deleted      bool
+	ValuePointer bool
+	Sequence     uint64
+	Err          error
 }
 
 type MultiGetResult struct {
@@ -1,6 +1,16 @@
 package model
 
+import "math"
+
+// LatestSequence reads the latest version of every key
+const LatestSequence uint64 = math.MaxUint64
+
+// KeyValuePair with ValuePointer set holds an encoded pointer to the value in the value log, instead of the value.
+// Sequence is the sequence number of the write, a higher sequence is a newer version of the key
 type KeyValuePair struct {
-	Key   Slice
-	Value Slice
+	Key          Slice
+	Value        Slice
+	Deleted      bool
+	ValuePointer bool
+	Sequence     uint64
 }
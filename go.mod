@@ -4,6 +4,7 @@ go 1.17
 
 require (
 	github.com/edsrzf/mmap-go v1.1.0
+	github.com/golang/snappy v0.0.4
 	github.com/spaolacci/murmur3 v1.1.0
 )
 
@@ -0,0 +1,15 @@
+package log
+
+import "fmt"
+
+// CorruptionError is returned when a transactional entry in the WAL is incomplete or fails its checksum
+type CorruptionError struct {
+	FileName string
+	Offset   int64
+	Reason   string
+	atTail   bool
+}
+
+func (corruptionError *CorruptionError) Error() string {
+	return fmt.Sprintf("corrupt WAL record in %v at offset %v: %v", corruptionError.FileName, corruptionError.Offset, corruptionError.Reason)
+}
@@ -6,12 +6,16 @@ import (
 	"os"
 	"path"
 	"sort"
+	"sync"
 )
 
+// WAL is written by a single goroutine, lock guards the passive segments which are removed by Checkpoint
 type WAL struct {
 	directory       string
 	activeSegment   *Segment
 	passiveSegments []*Segment
+	checksum        uint32
+	lock            sync.Mutex
 }
 
 const subDirectoryPermission = 0744
@@ -34,43 +38,63 @@ func NewLog(directory string, segmentMaxSizeBytes uint64) (*WAL, error) {
 	}
 }
 
-func (log *WAL) BeginTransactionHeader(totalSize uint16) error {
-	rollOverActiveSegment := func() error {
-		log.passiveSegments = append(log.passiveSegments, log.activeSegment)
-		return log.openActiveSegmentAt(log.activeSegment.LastOffset(), log.activeSegment.maxSizeBytes)
-	}
+func (log *WAL) BeginTransactionHeader(totalSize uint32, sequence uint64) error {
 	appendToActiveSegment := func() error {
-		if err := log.activeSegment.Append(NewPersistentLogSliceTransactionHeader(totalSize)); err != nil {
+		header := NewPersistentLogSliceTransactionHeader(totalSize, sequence)
+		if err := log.activeSegment.Append(header); err != nil {
 			return err
 		}
+		log.checksum = header.updateChecksum(0)
 		return nil
 	}
-	if log.activeSegment.IsMaxed() {
-		if err := rollOverActiveSegment(); err != nil {
-			return err
-		}
+	if err := log.mayBeRollOverActiveSegment(); err != nil {
+		return err
 	}
 	return appendToActiveSegment()
 }
 
+// AppendTransactions appends successful transactions, one for each of the entries, with a single write to the active segment.
+// The transactions get consecutive sequences beginning with the firstSequence
+func (log *WAL) AppendTransactions(allEntries []PersistentLogSlice, firstSequence uint64) error {
+	if err := log.mayBeRollOverActiveSegment(); err != nil {
+		return err
+	}
+	transactions := PersistentLogSlice{}
+	for index, entries := range allEntries {
+		transactions.Add(NewPersistentLogSliceTransaction(entries, firstSequence+uint64(index), TransactionStatusSuccess()))
+	}
+	return log.activeSegment.Append(transactions)
+}
+
 func (log *WAL) Append(persistentLogSlice PersistentLogSlice) error {
 	appendToActiveSegment := func() error {
-		//Assignment:WAL:1:append to the log
-		var err error = nil
-		if err != nil {
+		if err := log.activeSegment.Append(persistentLogSlice); err != nil {
 			return err
 		}
+		log.checksum = persistentLogSlice.updateChecksum(log.checksum)
 		return nil
 	}
 	return appendToActiveSegment()
 }
 
+// MarkTransactionWith ends the transaction with its status and the checksum of the transactional entry
 func (log *WAL) MarkTransactionWith(transactionStatus TransactionStatus) error {
-	return log.activeSegment.Append(PersistentLogSlice{contents: transactionStatus.Marshal()})
+	status := PersistentLogSlice{contents: transactionStatus.Marshal()}
+	checksum := NewPersistentLogSliceChecksum(status.updateChecksum(log.checksum))
+	status.Add(checksum)
+	return log.activeSegment.Append(status)
 }
 
 func (log *WAL) ReadAll() ([]TransactionalEntry, error) {
+	return log.ReadAllFrom(0)
+}
+
+// ReadAllFrom reads all the transactional entries which begin at or after the offset, the offset must be a transaction boundary
+func (log *WAL) ReadAllFrom(offset int64) ([]TransactionalEntry, error) {
 	allSegments := func() []*Segment {
+		log.lock.Lock()
+		defer log.lock.Unlock()
+
 		copiedPassiveSegments := make([]*Segment, len(log.passiveSegments))
 		copy(copiedPassiveSegments, log.passiveSegments)
 
@@ -79,7 +103,10 @@ func (log *WAL) ReadAll() ([]TransactionalEntry, error) {
 	readAllSegments := func() ([]TransactionalEntry, error) {
 		var allEntries []TransactionalEntry
 		for _, segment := range allSegments() {
-			if transactionalEntries, err := segment.ReadAll(); err != nil {
+			if segment.LastOffset() <= offset {
+				continue
+			}
+			if transactionalEntries, err := segment.ReadAllFrom(offset); err != nil {
 				return nil, err
 			} else {
 				allEntries = append(allEntries, transactionalEntries...)
@@ -90,11 +117,57 @@ func (log *WAL) ReadAll() ([]TransactionalEntry, error) {
 	return readAllSegments()
 }
 
-func (log *WAL) Close() {
-	log.activeSegment.Close()
+// LastOffset returns the offset at which the next transaction will begin
+func (log *WAL) LastOffset() int64 {
+	return log.activeSegment.LastOffset()
+}
+
+// Sync makes the transactions written to the active segment durable
+func (log *WAL) Sync() error {
+	return log.activeSegment.Sync()
+}
+
+// Checkpoint deletes the passive segments which end at or before the offset, their contents are persisted elsewhere
+func (log *WAL) Checkpoint(offset int64) error {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	for len(log.passiveSegments) > 0 && log.passiveSegments[0].LastOffset() <= offset {
+		if err := log.passiveSegments[0].Delete(); err != nil {
+			return err
+		}
+		log.passiveSegments = log.passiveSegments[1:]
+	}
+	return nil
+}
+
+// Close closes all the segments and returns the first error
+func (log *WAL) Close() error {
+	err := log.activeSegment.Close()
 	for _, segment := range log.passiveSegments {
-		segment.Close()
+		if closeErr := segment.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (log *WAL) mayBeRollOverActiveSegment() error {
+	if !log.activeSegment.IsMaxed() {
+		return nil
 	}
+	return log.rollOverActiveSegment()
+}
+
+func (log *WAL) rollOverActiveSegment() error {
+	//transactions written since the last sync must be durable before the segment becomes passive
+	if err := log.activeSegment.Sync(); err != nil {
+		return err
+	}
+	log.lock.Lock()
+	log.passiveSegments = append(log.passiveSegments, log.activeSegment)
+	log.lock.Unlock()
+	return log.openActiveSegmentAt(log.activeSegment.LastOffset(), log.activeSegment.maxSizeBytes)
 }
 
 func (log *WAL) init(segmentMaxSizeBytes uint64) error {
@@ -123,12 +196,20 @@ func (log *WAL) init(segmentMaxSizeBytes uint64) error {
 		if err := log.openActiveSegmentAt(offsets[len(offsets)-1], segmentMaxSizeBytes); err != nil {
 			return err
 		}
+		//only the active segment can end with a transactional entry which was being written when the process stopped
+		if err := log.activeSegment.Recover(); err != nil {
+			return err
+		}
 		for index := 0; index < len(offsets)-1; index++ {
 			segmentOffset := offsets[index]
 			if err := log.openPassiveSegmentAt(segmentOffset, segmentMaxSizeBytes); err != nil {
 				return err
 			}
 		}
+		//transactions are only appended in the current format, an active segment of an older version is retired after the older passive segments
+		if !log.activeSegment.IsOfCurrentVersion() {
+			return log.rollOverActiveSegment()
+		}
 		return nil
 	}
 	return reOpenSegments()
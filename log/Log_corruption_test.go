@@ -0,0 +1,277 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"storage-engine-workshop/db/model"
+	"testing"
+)
+
+func appendSuccessfulTransaction(wal *WAL, key string) {
+	persistentLogSlice := NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte(key)), Value: model.NewSlice([]byte("Value"))})
+	if err := wal.BeginTransactionHeader(uint32(persistentLogSlice.Size()), 1); err != nil {
+		log.Fatal(err)
+	}
+	if err := wal.Append(persistentLogSlice); err != nil {
+		log.Fatal(err)
+	}
+	if err := wal.MarkTransactionWith(TransactionStatusSuccess()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestTruncatesAPartiallyWrittenTransactionAtTheTailSimulatingACrash(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+	appendSuccessfulTransaction(wal, "Key-1")
+	appendSuccessfulTransaction(wal, "Key-2")
+	wal.Close()
+
+	segmentFilePath := path.Join(directory, "wal", "0.store")
+	stat, _ := os.Stat(segmentFilePath)
+	_ = os.Truncate(segmentFilePath, stat.Size()-3)
+
+	walAfterRestart, err := NewLog(directory, segmentMaxSizeBytes)
+	if err != nil {
+		t.Fatalf("Expected no error while recovering the WAL, received %v", err)
+	}
+	appendSuccessfulTransaction(walAfterRestart, "Key-3")
+
+	transactionalEntries, err := walAfterRestart.ReadAll()
+	if err != nil {
+		t.Fatalf("Expected no error while reading the recovered WAL, received %v", err)
+	}
+	if len(transactionalEntries) != 2 {
+		t.Fatalf("Expected %v transactional entries, received %v", 2, len(transactionalEntries))
+	}
+	for index, expectedKey := range []string{"Key-1", "Key-3"} {
+		if key := transactionalEntries[index].keyValuePairs[0].Key.GetSlice().AsString(); key != expectedKey {
+			t.Fatalf("Expected key to be %v received %v", expectedKey, key)
+		}
+	}
+}
+
+func TestTruncatesATransactionFailingItsChecksumAtTheTailSimulatingACrash(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+	appendSuccessfulTransaction(wal, "Key-1")
+	appendSuccessfulTransaction(wal, "Key-2")
+	wal.Close()
+
+	segmentFilePath := path.Join(directory, "wal", "0.store")
+	stat, _ := os.Stat(segmentFilePath)
+	flipByteAt(segmentFilePath, stat.Size()-10)
+
+	walAfterRestart, err := NewLog(directory, segmentMaxSizeBytes)
+	if err != nil {
+		t.Fatalf("Expected no error while recovering the WAL, received %v", err)
+	}
+	transactionalEntries, _ := walAfterRestart.ReadAll()
+	if len(transactionalEntries) != 1 {
+		t.Fatalf("Expected %v transactional entry, received %v", 1, len(transactionalEntries))
+	}
+}
+
+func TestReportsCorruptionInTheMiddleOfTheWAL(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+	appendSuccessfulTransaction(wal, "Key-1")
+	appendSuccessfulTransaction(wal, "Key-2")
+	wal.Close()
+
+	flipByteAt(path.Join(directory, "wal", "0.store"), 10)
+
+	_, err := NewLog(directory, segmentMaxSizeBytes)
+	var corruptionError *CorruptionError
+	if !errors.As(err, &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while opening the WAL, received %v", err)
+	}
+	if corruptionError.Offset != segmentHeaderSize {
+		t.Fatalf("Expected corruption to be reported at offset %v, received %v", segmentHeaderSize, corruptionError.Offset)
+	}
+}
+
+func TestReportsACorruptTransactionSizeFollowedByCompleteTransactions(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+	appendSuccessfulTransaction(wal, "Key-1")
+	appendSuccessfulTransaction(wal, "Key-2")
+	wal.Close()
+
+	//the size of the first transaction points beyond the end of the segment
+	flipByteAt(path.Join(directory, "wal", "0.store"), segmentHeaderSize)
+
+	_, err := NewLog(directory, segmentMaxSizeBytes)
+	var corruptionError *CorruptionError
+	if !errors.As(err, &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while opening the WAL, received %v", err)
+	}
+	if corruptionError.Offset != segmentHeaderSize {
+		t.Fatalf("Expected corruption to be reported at offset %v, received %v", segmentHeaderSize, corruptionError.Offset)
+	}
+	stat, _ := os.Stat(path.Join(directory, "wal", "0.store"))
+	if stat.Size() <= segmentHeaderSize {
+		t.Fatalf("Expected the transactions after the corrupt size not to be truncated, received a segment of %v bytes", stat.Size())
+	}
+}
+
+func TestReportsCorruptionInAPassiveSegment(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 16
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+	appendSuccessfulTransaction(wal, "Key-1")
+	appendSuccessfulTransaction(wal, "Key-2")
+	wal.Close()
+
+	flipByteAt(path.Join(directory, "wal", "0.store"), 10)
+
+	walAfterRestart, err := NewLog(directory, segmentMaxSizeBytes)
+	if err != nil {
+		t.Fatalf("Expected no error while opening the WAL, received %v", err)
+	}
+	_, err = walAfterRestart.ReadAll()
+	var corruptionError *CorruptionError
+	if !errors.As(err, &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while reading the WAL, received %v", err)
+	}
+}
+
+func flipByteAt(filePath string, offset int64) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	bytes := make([]byte, 1)
+	_, _ = file.ReadAt(bytes, offset)
+	bytes[0] = bytes[0] ^ 0xff
+	_, _ = file.WriteAt(bytes, offset)
+}
+
+// legacyTransaction encodes a transaction as written before the segment header was introduced:
+// 2 bytes size | 4 bytes entrySize | 4 bytes keySize | key | value | status
+func legacyTransaction(key string) []byte {
+	value := []byte("Value")
+	entry := make([]byte, int(reservedEntrySize)+int(reservedKeySize))
+	bigEndian.PutUint32(entry, uint32(len(entry)+len(key)+len(value)))
+	bigEndian.PutUint32(entry[reservedEntrySize:], uint32(len(key)))
+	entry = append(append(entry, key...), value...)
+
+	transaction := make([]byte, legacyTransactionHeaderSize)
+	bigEndian.PutUint16(transaction, uint16(len(entry)))
+	transaction = append(transaction, entry...)
+	return append(transaction, TransactionStatusSuccess().Marshal()...)
+}
+
+func TestReadsALegacySegmentAndAppendsToANewSegment(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	_ = os.Mkdir(path.Join(directory, "wal"), subDirectoryPermission)
+	_ = ioutil.WriteFile(path.Join(directory, "wal", "0.store"), legacyTransaction("Key-1"), 0644)
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, err := NewLog(directory, segmentMaxSizeBytes)
+	if err != nil {
+		t.Fatalf("Expected no error while opening a WAL with a legacy segment, received %v", err)
+	}
+	appendSuccessfulTransaction(wal, "Key-2")
+
+	transactionalEntries, err := wal.ReadAll()
+	if err != nil {
+		t.Fatalf("Expected no error while reading a WAL with a legacy segment, received %v", err)
+	}
+	for index, expectedKey := range []string{"Key-1", "Key-2"} {
+		if key := transactionalEntries[index].keyValuePairs[0].Key.GetSlice().AsString(); key != expectedKey {
+			t.Fatalf("Expected key to be %v received %v", expectedKey, key)
+		}
+	}
+	if wal.activeSegment.IsLegacy() {
+		t.Fatalf("Expected transactions not to be appended to a legacy segment")
+	}
+}
+
+func TestReadsMultipleLegacySegmentsInOrderAndCheckpointsThem(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	_ = os.Mkdir(path.Join(directory, "wal"), subDirectoryPermission)
+	var segmentOffset int64
+	for _, key := range []string{"Key-1", "Key-2", "Key-3"} {
+		transaction := legacyTransaction(key)
+		_ = ioutil.WriteFile(path.Join(directory, "wal", fmt.Sprintf("%v.store", segmentOffset)), transaction, 0644)
+		segmentOffset = segmentOffset + int64(len(transaction))
+	}
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, err := NewLog(directory, segmentMaxSizeBytes)
+	if err != nil {
+		t.Fatalf("Expected no error while opening a WAL with legacy segments, received %v", err)
+	}
+	appendSuccessfulTransaction(wal, "Key-4")
+
+	transactionalEntries, err := wal.ReadAll()
+	if err != nil {
+		t.Fatalf("Expected no error while reading a WAL with legacy segments, received %v", err)
+	}
+	for index, expectedKey := range []string{"Key-1", "Key-2", "Key-3", "Key-4"} {
+		if key := transactionalEntries[index].keyValuePairs[0].Key.GetSlice().AsString(); key != expectedKey {
+			t.Fatalf("Expected key to be %v received %v", expectedKey, key)
+		}
+	}
+
+	if err := wal.Checkpoint(segmentOffset); err != nil {
+		t.Fatalf("Expected no error while checkpointing a WAL with legacy segments, received %v", err)
+	}
+	segmentFiles, _ := ioutil.ReadDir(path.Join(directory, "wal"))
+	if len(segmentFiles) != 1 {
+		t.Fatalf("Expected %v segment to remain after the checkpoint, received %v", 1, len(segmentFiles))
+	}
+	if segmentFiles[0].Name() != fmt.Sprintf("%v.store", segmentOffset) {
+		t.Fatalf("Expected %v, received %v", fmt.Sprintf("%v.store", segmentOffset), segmentFiles[0].Name())
+	}
+}
+
+func TestAppendsATransactionLargerThan64KB(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+
+	value := make([]byte, 3*1024*1024)
+	value[len(value)-1] = 'v'
+	persistentLogSlice := NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte("Blob")), Value: model.NewSlice(value)})
+	if err := wal.AppendTransactions([]PersistentLogSlice{persistentLogSlice}, 1); err != nil {
+		log.Fatal(err)
+	}
+	wal.Close()
+
+	walAfterRestart, _ := NewLog(directory, segmentMaxSizeBytes)
+	transactionalEntries, err := walAfterRestart.ReadAll()
+	if err != nil {
+		t.Fatalf("Expected no error while reading the WAL, received %v", err)
+	}
+	if size := len(transactionalEntries[0].keyValuePairs[0].Value.GetPersistentContents()); size != len(value) {
+		t.Fatalf("Expected value of size %v, received %v", len(value), size)
+	}
+}
@@ -28,7 +28,7 @@ func TestAppendsASuccessfulTransactionalEntryAndReadsIt(t *testing.T) {
 	persistentLogSlice := NewPersistentLogSlice(model.KeyValuePair{Key: key, Value: value})
 	allEntriesSize := persistentLogSlice.Size()
 
-	if err := wal.BeginTransactionHeader(uint16(allEntriesSize)); err != nil {
+	if err := wal.BeginTransactionHeader(uint32(allEntriesSize), 1); err != nil {
 		log.Fatal(err)
 	}
 	if err := wal.Append(persistentLogSlice); err != nil {
@@ -65,7 +65,7 @@ func TestAppendsAFailedTransactionalEntryAndReadsIt(t *testing.T) {
 	persistentLogSlice := NewPersistentLogSlice(model.KeyValuePair{Key: key, Value: value})
 	allEntriesSize := persistentLogSlice.Size()
 
-	if err := wal.BeginTransactionHeader(uint16(allEntriesSize)); err != nil {
+	if err := wal.BeginTransactionHeader(uint32(allEntriesSize), 1); err != nil {
 		log.Fatal(err)
 	}
 	if err := wal.Append(persistentLogSlice); err != nil {
@@ -111,7 +111,7 @@ func TestAppendsMultipleSuccessEntriesWithinOneTransactionAndReadsAllOfThem(t *t
 	var segmentMaxSizeBytes uint64 = 32
 	wal, _ := NewLog(directory, segmentMaxSizeBytes)
 
-	if err := wal.BeginTransactionHeader(uint16(persistentLogSlice.Size())); err != nil {
+	if err := wal.BeginTransactionHeader(uint32(persistentLogSlice.Size()), 1); err != nil {
 		log.Fatal(err)
 	}
 	if err := wal.Append(persistentLogSlice); err != nil {
@@ -161,7 +161,7 @@ func TestAppendsMultipleFailedEntriesWithinOneTransactionAndReadsAllOfThem(t *te
 	var segmentMaxSizeBytes uint64 = 32
 	wal, _ := NewLog(directory, segmentMaxSizeBytes)
 
-	if err := wal.BeginTransactionHeader(uint16(persistentLogSlice.Size())); err != nil {
+	if err := wal.BeginTransactionHeader(uint32(persistentLogSlice.Size()), 1); err != nil {
 		log.Fatal(err)
 	}
 	if err := wal.Append(persistentLogSlice); err != nil {
@@ -212,7 +212,7 @@ func TestAppendsMultipleSuccessEntriesWithinMultipleTransactionsAndReadsAllOfThe
 			persistentLogSlice.Add(NewPersistentLogSlice(model.KeyValuePair{Key: key, Value: value}))
 		}
 
-		if err := wal.BeginTransactionHeader(uint16(persistentLogSlice.Size())); err != nil {
+		if err := wal.BeginTransactionHeader(uint32(persistentLogSlice.Size()), 1); err != nil {
 			log.Fatal(err)
 		}
 		if err := wal.Append(persistentLogSlice); err != nil {
@@ -248,3 +248,175 @@ func TestAppendsMultipleSuccessEntriesWithinMultipleTransactionsAndReadsAllOfThe
 	assertEntries(0, 0, 0, 20)
 	assertEntries(1, 20, 0, 20)
 }
+
+func TestAppendsATombstoneWithinATransactionAndReadsIt(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 32
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+
+	persistentLogSlice := PersistentLogSlice{}
+	persistentLogSlice.Add(NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte("Key")), Value: model.NewSlice([]byte("Value"))}))
+	persistentLogSlice.Add(NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte("Key")), Value: model.NilSlice(), Deleted: true}))
+
+	if err := wal.BeginTransactionHeader(uint32(persistentLogSlice.Size()), 1); err != nil {
+		log.Fatal(err)
+	}
+	if err := wal.Append(persistentLogSlice); err != nil {
+		log.Fatal(err)
+	}
+	if err := wal.MarkTransactionWith(TransactionStatusSuccess()); err != nil {
+		log.Fatal(err)
+	}
+
+	transactionalEntries, err := wal.ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+	onlyEntry := transactionalEntries[0]
+	if onlyEntry.keyValuePairs[0].Deleted {
+		t.Fatalf("Expected first key value pair to be a put, received a tombstone")
+	}
+	if !onlyEntry.keyValuePairs[1].Deleted {
+		t.Fatalf("Expected second key value pair to be a tombstone, received a put")
+	}
+	if onlyEntry.keyValuePairs[1].Key.GetSlice().AsString() != "Key" {
+		t.Fatalf("Expected key to be %v received %v", "Key", onlyEntry.keyValuePairs[1].Key.GetSlice().AsString())
+	}
+}
+
+func TestReadsTransactionalEntriesFromAnOffset(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 32
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+
+	appendTransaction := func(key string) {
+		persistentLogSlice := NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte(key)), Value: model.NewSlice([]byte("Value"))})
+		if err := wal.BeginTransactionHeader(uint32(persistentLogSlice.Size()), 1); err != nil {
+			log.Fatal(err)
+		}
+		if err := wal.Append(persistentLogSlice); err != nil {
+			log.Fatal(err)
+		}
+		if err := wal.MarkTransactionWith(TransactionStatusSuccess()); err != nil {
+			log.Fatal(err)
+		}
+	}
+	appendTransaction("Key-1")
+	offset := wal.LastOffset()
+	appendTransaction("Key-2")
+	appendTransaction("Key-3")
+
+	transactionalEntries, err := wal.ReadAllFrom(offset)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(transactionalEntries) != 2 {
+		t.Fatalf("Expected %v transactional entries, received %v", 2, len(transactionalEntries))
+	}
+	for index, expectedKey := range []string{"Key-2", "Key-3"} {
+		if key := transactionalEntries[index].keyValuePairs[0].Key.GetSlice().AsString(); key != expectedKey {
+			t.Fatalf("Expected key to be %v received %v", expectedKey, key)
+		}
+	}
+}
+
+func TestDeletesSegmentsBeforeTheCheckpoint(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 16
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+
+	appendTransaction := func(key string) {
+		persistentLogSlice := NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte(key)), Value: model.NewSlice([]byte("Value"))})
+		if err := wal.BeginTransactionHeader(uint32(persistentLogSlice.Size()), 1); err != nil {
+			log.Fatal(err)
+		}
+		if err := wal.Append(persistentLogSlice); err != nil {
+			log.Fatal(err)
+		}
+		if err := wal.MarkTransactionWith(TransactionStatusSuccess()); err != nil {
+			log.Fatal(err)
+		}
+	}
+	appendTransaction("Key-1")
+	appendTransaction("Key-2")
+	offset := wal.LastOffset()
+	appendTransaction("Key-3")
+
+	if err := wal.Checkpoint(offset); err != nil {
+		t.Fatalf("Expected no error while checkpointing the WAL, received %v", err)
+	}
+	segmentFiles, _ := ioutil.ReadDir(wal.directory)
+	if len(segmentFiles) != 1 {
+		t.Fatalf("Expected %v segment to remain after checkpoint, received %v", 1, len(segmentFiles))
+	}
+	transactionalEntries, _ := wal.ReadAll()
+	if len(transactionalEntries) != 1 || transactionalEntries[0].keyValuePairs[0].Key.GetSlice().AsString() != "Key-3" {
+		t.Fatalf("Expected only the transaction after the checkpoint to remain")
+	}
+}
+
+func TestAppendsMultipleTransactionsAndReadsThem(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 32
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+
+	var allEntries []PersistentLogSlice
+	for _, key := range []string{"Key-1", "Key-2", "Key-3"} {
+		allEntries = append(allEntries, NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte(key)), Value: model.NewSlice([]byte("Value"))}))
+	}
+	if err := wal.AppendTransactions(allEntries, 1); err != nil {
+		log.Fatal(err)
+	}
+
+	transactionalEntries, err := wal.ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(transactionalEntries) != 3 {
+		t.Fatalf("Expected %v transactional entries, received %v", 3, len(transactionalEntries))
+	}
+	for index, expectedKey := range []string{"Key-1", "Key-2", "Key-3"} {
+		if !transactionalEntries[index].IsSuccess() {
+			t.Fatalf("Expected status to be success, received failed")
+		}
+		if key := transactionalEntries[index].keyValuePairs[0].Key.GetSlice().AsString(); key != expectedKey {
+			t.Fatalf("Expected key to be %v received %v", expectedKey, key)
+		}
+	}
+}
+
+func TestReadsTheSequencesOfTransactionsSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	var segmentMaxSizeBytes uint64 = 1024
+	wal, _ := NewLog(directory, segmentMaxSizeBytes)
+
+	var allEntries []PersistentLogSlice
+	for _, key := range []string{"Key-1", "Key-2", "Key-3"} {
+		allEntries = append(allEntries, NewPersistentLogSlice(model.KeyValuePair{Key: model.NewSlice([]byte(key)), Value: model.NewSlice([]byte("Value"))}))
+	}
+	if err := wal.AppendTransactions(allEntries, 41); err != nil {
+		log.Fatal(err)
+	}
+	wal.Close()
+
+	walAfterRestart, _ := NewLog(directory, segmentMaxSizeBytes)
+	transactionalEntries, err := walAfterRestart.ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for index, expectedSequence := range []uint64{41, 42, 43} {
+		if sequence := transactionalEntries[index].Sequence(); sequence != expectedSequence {
+			t.Fatalf("Expected sequence to be %v received %v", expectedSequence, sequence)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package log
 
 type PersistentKeyValuePair struct {
-	Key   PersistentLogSlice
-	Value PersistentLogSlice
+	Key          PersistentLogSlice
+	Value        PersistentLogSlice
+	Deleted      bool
+	ValuePointer bool
 }
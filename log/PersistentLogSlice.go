@@ -2,6 +2,7 @@ package log
 
 import (
 	"encoding/binary"
+	"hash/crc32"
 	"storage-engine-workshop/db/model"
 	"unsafe"
 )
@@ -10,13 +11,25 @@ var (
 	bigEndian                           = binary.BigEndian
 	reservedEntrySize                   = unsafe.Sizeof(uint32(0))
 	reservedKeySize                     = unsafe.Sizeof(uint32(0))
-	reservedTransactionHeaderSize uint8 = 2
+	reservedKindSize                    = unsafe.Sizeof(uint8(0))
+	reservedTransactionHeaderSize uint8 = 4
+	legacyTransactionHeaderSize   uint8 = 2
+	reservedSequenceSize          uint8 = 8
 	reservedTransactionStatusSize uint8 = TransactionStatusSize()
+	reservedChecksumSize          uint8 = 4
+	crc32Table                          = crc32.MakeTable(crc32.Castagnoli)
+)
+
+const (
+	kindPut          byte = 0
+	kindDelete       byte = 1
+	kindValuePointer byte = 2
 )
 
 type TransactionalEntry struct {
 	keyValuePairs []PersistentKeyValuePair
 	status        TransactionStatus
+	sequence      uint64
 }
 
 type PersistentLogSlice struct {
@@ -28,12 +41,19 @@ func NewPersistentLogSlice(keyValuePair model.KeyValuePair) PersistentLogSlice {
 }
 
 func NewPersistentLogSliceKeyValuePairs(contents []byte) []PersistentKeyValuePair {
-	return unmarshal(contents)
+	return unmarshal(contents, true)
+}
+
+// NewLegacyPersistentLogSliceKeyValuePairs decodes the entries of a legacy segment, which carry no kind
+func NewLegacyPersistentLogSliceKeyValuePairs(contents []byte) []PersistentKeyValuePair {
+	return unmarshal(contents, false)
 }
 
-func NewPersistentLogSliceTransactionHeader(totalSize uint16) PersistentLogSlice {
-	bytes := make([]byte, reservedTransactionHeaderSize)
-	bigEndian.PutUint16(bytes, totalSize)
+// NewPersistentLogSliceTransactionHeader encodes 4 bytes total size of the entries | 8 bytes sequence of the transaction
+func NewPersistentLogSliceTransactionHeader(totalSize uint32, sequence uint64) PersistentLogSlice {
+	bytes := make([]byte, reservedTransactionHeaderSize+reservedSequenceSize)
+	bigEndian.PutUint32(bytes, totalSize)
+	bigEndian.PutUint64(bytes[reservedTransactionHeaderSize:], sequence)
 	return PersistentLogSlice{contents: bytes}
 }
 
@@ -53,8 +73,46 @@ func (persistentLogSlice *PersistentLogSlice) Add(other PersistentLogSlice) {
 	persistentLogSlice.contents = append(persistentLogSlice.contents, other.contents...)
 }
 
-func TransactionalEntrySize(bytes []byte) uint16 {
-	return bigEndian.Uint16(bytes)
+func (transactionalEntry TransactionalEntry) AllKeyValuePairs() []PersistentKeyValuePair {
+	return transactionalEntry.keyValuePairs
+}
+
+func (transactionalEntry TransactionalEntry) IsSuccess() bool {
+	return transactionalEntry.status.isSuccess()
+}
+
+// Sequence returns the sequence of the transaction, which is 0 for transactions written before sequences were introduced
+func (transactionalEntry TransactionalEntry) Sequence() uint64 {
+	return transactionalEntry.sequence
+}
+
+// NewPersistentLogSliceTransaction encodes a complete transactional entry: header | entries | status | checksum
+func NewPersistentLogSliceTransaction(entries PersistentLogSlice, sequence uint64, transactionStatus TransactionStatus) PersistentLogSlice {
+	transaction := NewPersistentLogSliceTransactionHeader(uint32(entries.Size()), sequence)
+	transaction.Add(entries)
+	transaction.Add(PersistentLogSlice{contents: transactionStatus.Marshal()})
+	transaction.Add(NewPersistentLogSliceChecksum(transaction.updateChecksum(0)))
+	return transaction
+}
+
+// NewPersistentLogSliceChecksum encodes the checksum which ends a transactional entry.
+// A transactional entry is: 12 bytes header | entries | 7 bytes status | 4 bytes crc32c of header, entries and status.
+// Segments written before the segment header was introduced have a 2 bytes transaction header, entries without a kind and no checksum.
+func NewPersistentLogSliceChecksum(checksum uint32) PersistentLogSlice {
+	bytes := make([]byte, reservedChecksumSize)
+	bigEndian.PutUint32(bytes, checksum)
+	return PersistentLogSlice{contents: bytes}
+}
+
+func (persistentLogSlice PersistentLogSlice) updateChecksum(checksum uint32) uint32 {
+	return crc32.Update(checksum, crc32Table, persistentLogSlice.contents)
+}
+
+func TransactionalEntrySize(bytes []byte) uint32 {
+	if len(bytes) == int(legacyTransactionHeaderSize) {
+		return uint32(bigEndian.Uint16(bytes))
+	}
+	return bigEndian.Uint32(bytes)
 }
 
 func marshal(keyValuePair model.KeyValuePair) PersistentLogSlice {
@@ -62,9 +120,10 @@ func marshal(keyValuePair model.KeyValuePair) PersistentLogSlice {
 		len(keyValuePair.Key.GetRawContent()) +
 			len(keyValuePair.Value.GetRawContent()) +
 			int(reservedKeySize) +
+			int(reservedKindSize) +
 			int(reservedEntrySize)
 
-	//The way PutCommand is encoded is: 4 bytes for entrySize | 4 bytes for keySize | Key content | Value content
+	//The way PutCommand is encoded is: 4 bytes for entrySize | 4 bytes for keySize | 1 byte for kind | Key content | Value content
 	bytes := make([]byte, entrySize)
 	offset := 0
 
@@ -74,6 +133,9 @@ func marshal(keyValuePair model.KeyValuePair) PersistentLogSlice {
 	bigEndian.PutUint32(bytes[offset:], uint32(len(keyValuePair.Key.GetRawContent())))
 	offset = offset + int(reservedKeySize)
 
+	bytes[offset] = kindOf(keyValuePair)
+	offset = offset + int(reservedKindSize)
+
 	copy(bytes[offset:], keyValuePair.Key.GetRawContent())
 	offset = offset + len(keyValuePair.Key.GetRawContent())
 
@@ -81,7 +143,7 @@ func marshal(keyValuePair model.KeyValuePair) PersistentLogSlice {
 	return PersistentLogSlice{contents: bytes}
 }
 
-func unmarshal(bytes []byte) []PersistentKeyValuePair {
+func unmarshal(bytes []byte, withKind bool) []PersistentKeyValuePair {
 	var keyValuePairs []PersistentKeyValuePair
 
 	length := uint32(len(bytes))
@@ -93,6 +155,11 @@ func unmarshal(bytes []byte) []PersistentKeyValuePair {
 		index = index + uint32(reservedEntrySize)
 		keySize := bigEndian.Uint32(bytes[index:])
 		index = index + uint32(reservedKeySize)
+		kind := kindPut
+		if withKind {
+			kind = bytes[index]
+			index = index + uint32(reservedKindSize)
+		}
 
 		keyEndOffset := index + keySize
 		key := bytes[index:keyEndOffset]
@@ -104,10 +171,22 @@ func unmarshal(bytes []byte) []PersistentKeyValuePair {
 
 		keyValuePairs = append(keyValuePairs,
 			PersistentKeyValuePair{
-				Key:   PersistentLogSlice{contents: key},
-				Value: PersistentLogSlice{contents: value},
+				Key:          PersistentLogSlice{contents: key},
+				Value:        PersistentLogSlice{contents: value},
+				Deleted:      kind == kindDelete,
+				ValuePointer: kind == kindValuePointer,
 			},
 		)
 	}
 	return keyValuePairs
 }
+
+func kindOf(keyValuePair model.KeyValuePair) byte {
+	if keyValuePair.Deleted {
+		return kindDelete
+	}
+	if keyValuePair.ValuePointer {
+		return kindValuePointer
+	}
+	return kindPut
+}
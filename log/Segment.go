@@ -1,6 +1,7 @@
 package log
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"path"
@@ -29,9 +30,7 @@ func NewSegment(directory string, baseOffset int64, maxSizeBytes uint64) (*Segme
 }
 
 func (segment *Segment) Append(persistentLogSlice PersistentLogSlice) error {
-	//Assignment:WAL:2:append to the segment
-	var err error = nil
-	if err != nil {
+	if err := segment.store.Append(persistentLogSlice); err != nil {
 		return err
 	}
 	return nil
@@ -41,6 +40,35 @@ func (segment *Segment) ReadAll() ([]TransactionalEntry, error) {
 	return segment.store.ReadAll()
 }
 
+func (segment *Segment) ReadAllFrom(offset int64) ([]TransactionalEntry, error) {
+	if offset <= segment.baseOffSet {
+		return segment.store.ReadAll()
+	}
+	return segment.store.ReadAllFrom(offset - segment.baseOffSet)
+}
+
+// Recover truncates a partially written or corrupt transactional entry at the end of the segment.
+// Damage before the last transactional entry is returned as a CorruptionError
+func (segment *Segment) Recover() error {
+	_, err := segment.store.ReadAll()
+	if err == nil {
+		return nil
+	}
+	var corruptionError *CorruptionError
+	if errors.As(err, &corruptionError) && corruptionError.atTail {
+		return segment.store.Truncate(corruptionError.Offset)
+	}
+	return err
+}
+
+func (segment *Segment) IsLegacy() bool {
+	return segment.store.version == legacyVersion
+}
+
+func (segment *Segment) IsOfCurrentVersion() bool {
+	return segment.store.version == currentVersion
+}
+
 func (segment *Segment) IsMaxed() bool {
 	if segment.store.Size() >= int64(segment.maxSizeBytes) {
 		return true
@@ -52,8 +80,16 @@ func (segment *Segment) LastOffset() int64 {
 	return segment.store.Size() + segment.baseOffSet
 }
 
-func (segment *Segment) Close() {
-	segment.store.Close()
+func (segment *Segment) Sync() error {
+	return segment.store.Sync()
+}
+
+func (segment *Segment) Close() error {
+	return segment.store.Close()
+}
+
+func (segment *Segment) Delete() error {
+	return segment.store.Delete()
 }
 
 func parseSegmentFileName(file fs.FileInfo) int64 {
@@ -3,13 +3,24 @@ package log
 import (
 	"errors"
 	"fmt"
-	"log"
+	"hash/crc32"
 	"os"
 )
 
+const (
+	segmentMagic      uint32 = 0x57414c53
+	legacyVersion     uint16 = 1
+	currentVersion    uint16 = 2
+	segmentHeaderSize        = 6
+)
+
+// Store begins with a header: 4 bytes magic | 2 bytes version, followed by the transactional entries.
+// Stores without the header are of the legacy version, their transactional entries have a 2 bytes size header,
+// entries without a kind and no checksum.
 type Store struct {
-	file *os.File
-	size int64
+	file    *os.File
+	size    int64
+	version uint16
 }
 
 func NewStore(filePath string) (*Store, error) {
@@ -21,13 +32,18 @@ func NewStore(filePath string) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Store{file: storeFile, size: stat.Size()}, nil
+	store := &Store{file: storeFile, size: stat.Size()}
+	if err := store.init(); err != nil {
+		return nil, err
+	}
+	return store, nil
 }
 
 func (store *Store) Append(persistentLogSlice PersistentLogSlice) error {
-	////Assignment:WAL:3:append to the file
-	bytesWritten, err := store.file.Write(nil)
+	bytesWritten, err := store.file.Write(persistentLogSlice.GetPersistentContents())
 	if err != nil {
+		//a partially written slice would otherwise be followed by the next append
+		_ = store.file.Truncate(store.size)
 		return err
 	}
 	if bytesWritten <= 0 {
@@ -41,8 +57,15 @@ func (store *Store) Append(persistentLogSlice PersistentLogSlice) error {
 }
 
 func (store *Store) ReadAll() ([]TransactionalEntry, error) {
+	return store.ReadAllFrom(0)
+}
+
+func (store *Store) ReadAllFrom(offset int64) ([]TransactionalEntry, error) {
 	var entries []TransactionalEntry
-	var currentOffset int64 = 0
+	var currentOffset = offset
+	if currentOffset < store.beginOffset() {
+		currentOffset = store.beginOffset()
+	}
 
 	for currentOffset < store.size {
 		transactionalEntries, nextOffset, err := store.readAt(currentOffset)
@@ -55,39 +78,182 @@ func (store *Store) ReadAll() ([]TransactionalEntry, error) {
 	return entries, nil
 }
 
+func (store *Store) Sync() error {
+	return store.file.Sync()
+}
+
 func (store *Store) Size() int64 {
 	return store.size
 }
 
-func (store *Store) Close() {
-	err := store.file.Close()
-	if err != nil {
-		log.Default().Println("Error while closing the file " + store.file.Name())
+func (store *Store) Close() error {
+	return store.file.Close()
+}
+
+// Truncate drops the contents of the store from the offset onwards
+func (store *Store) Truncate(offset int64) error {
+	if err := store.file.Truncate(offset); err != nil {
+		return err
+	}
+	store.size = offset
+	return nil
+}
+
+func (store *Store) Delete() error {
+	_ = store.Close()
+	return os.Remove(store.file.Name())
+}
+
+func (store *Store) init() error {
+	writeHeader := func() error {
+		header := make([]byte, segmentHeaderSize)
+		bigEndian.PutUint32(header, segmentMagic)
+		bigEndian.PutUint16(header[4:], currentVersion)
+		store.version = currentVersion
+		return store.Append(PersistentLogSlice{contents: header})
+	}
+	//a store smaller than the header was created but its header was not written completely
+	if store.size < segmentHeaderSize {
+		if err := store.Truncate(0); err != nil {
+			return err
+		}
+		return writeHeader()
+	}
+	header := make([]byte, segmentHeaderSize)
+	if _, err := store.file.ReadAt(header, 0); err != nil {
+		return err
 	}
+	//a legacy store begins with the 2 bytes transaction size followed by the 4 bytes size of an entry smaller than 64KB,
+	//the third and the fourth bytes of a legacy store are zero and can never match the magic
+	if bigEndian.Uint32(header) != segmentMagic {
+		store.version = legacyVersion
+		return nil
+	}
+	store.version = bigEndian.Uint16(header[4:])
+	if store.version > currentVersion {
+		return errors.New(fmt.Sprintf("WAL segment %v has version %v, supported version is %v", store.file.Name(), store.version, currentVersion))
+	}
+	return nil
 }
 
+func (store *Store) beginOffset() int64 {
+	if store.version == legacyVersion {
+		return 0
+	}
+	return segmentHeaderSize
+}
+
+func (store *Store) transactionHeaderSize() uint8 {
+	if store.version == legacyVersion {
+		return legacyTransactionHeaderSize
+	}
+	return reservedTransactionHeaderSize + reservedSequenceSize
+}
+
+func (store *Store) checksumSize() uint8 {
+	if store.version == legacyVersion {
+		return 0
+	}
+	return reservedChecksumSize
+}
+
+// readAt reads the transactional entry at the offset after verifying its checksum, entries of a legacy store carry no checksum.
+// An entry which is incomplete or fails its checksum is at the tail only if no complete entry follows it,
+// a corrupt size which makes an entry end beyond the store does not hide the entries after it
 func (store *Store) readAt(offset int64) (TransactionalEntry, int64, error) {
-	transactionEntrySizeBytes := make([]byte, reservedTransactionHeaderSize)
-	_, err := store.file.ReadAt(transactionEntrySizeBytes, offset)
+	corruptionError := func(reason string, atTail bool) *CorruptionError {
+		return &CorruptionError{FileName: store.file.Name(), Offset: offset, Reason: reason, atTail: atTail}
+	}
+	transactionHeaderSize := store.transactionHeaderSize()
+	bytes, endOffset, err := store.readEntryAt(offset)
 	if err != nil {
 		return TransactionalEntry{}, -1, err
 	}
-	transactionEntrySize := TransactionalEntrySize(transactionEntrySizeBytes)
-	transactionEntryBytes := make([]byte, transactionEntrySize)
-	offset = offset + int64(reservedTransactionHeaderSize)
+	if bytes == nil {
+		if endOffset <= store.size {
+			return TransactionalEntry{}, -1, corruptionError("incomplete transaction header", true)
+		}
+		followed, err := store.hasEntryAfter(offset)
+		if err != nil {
+			return TransactionalEntry{}, -1, err
+		}
+		return TransactionalEntry{}, -1, corruptionError("incomplete transactional entry", !followed)
+	}
+	if store.version == legacyVersion {
+		statusOffset := len(bytes) - int(reservedTransactionStatusSize)
+		pairs := NewLegacyPersistentLogSliceKeyValuePairs(bytes[transactionHeaderSize:statusOffset])
+		return TransactionalEntry{keyValuePairs: pairs, status: TransactionStatusFrom(bytes[statusOffset:])}, endOffset, nil
+	}
+	if !checksumMatches(bytes) {
+		return TransactionalEntry{}, -1, corruptionError("checksum mismatch", endOffset >= store.size)
+	}
+	checksumOffset := len(bytes) - int(reservedChecksumSize)
+	statusOffset := checksumOffset - int(reservedTransactionStatusSize)
+	pairs := NewPersistentLogSliceKeyValuePairs(bytes[transactionHeaderSize:statusOffset])
+	sequence := bigEndian.Uint64(bytes[reservedTransactionHeaderSize:])
+	return TransactionalEntry{keyValuePairs: pairs, status: TransactionStatusFrom(bytes[statusOffset:checksumOffset]), sequence: sequence}, endOffset, nil
+}
 
-	bytesRead, err := store.file.ReadAt(transactionEntryBytes, offset)
-	if err != nil {
-		return TransactionalEntry{}, -1, err
+// readEntryAt returns the bytes of the transactional entry at the offset along with the offset where it ends.
+// It returns nil bytes for an entry which is incomplete, along with the end of the store for an incomplete header
+func (store *Store) readEntryAt(offset int64) ([]byte, int64, error) {
+	transactionHeaderSize := store.transactionHeaderSize()
+	if offset+int64(transactionHeaderSize) > store.size {
+		return nil, store.size, nil
 	}
-	offset = offset + int64(bytesRead)
-	pairs := NewPersistentLogSliceKeyValuePairs(transactionEntryBytes)
+	transactionEntrySizeBytes := make([]byte, transactionHeaderSize)
+	if _, err := store.file.ReadAt(transactionEntrySizeBytes, offset); err != nil {
+		return nil, -1, err
+	}
+	transactionEntrySize := int64(TransactionalEntrySize(transactionEntrySizeBytes))
+	endOffset := offset +
+		int64(transactionHeaderSize) +
+		transactionEntrySize +
+		int64(reservedTransactionStatusSize) +
+		int64(store.checksumSize())
 
-	transactionStatusBytes := make([]byte, reservedTransactionStatusSize)
-	_, err = store.file.ReadAt(transactionStatusBytes, offset)
-	if err != nil {
-		return TransactionalEntry{}, -1, err
+	if endOffset > store.size {
+		return nil, endOffset, nil
+	}
+	bytes := make([]byte, endOffset-offset)
+	if _, err := store.file.ReadAt(bytes, offset); err != nil {
+		return nil, -1, err
+	}
+	return bytes, endOffset, nil
+}
+
+// hasEntryAfter returns true if a complete transactional entry, which passes its checksum, begins after the offset.
+// Entries of a legacy store carry no checksum, an incomplete entry of a legacy store is always at the tail
+func (store *Store) hasEntryAfter(offset int64) (bool, error) {
+	if store.version == legacyVersion || offset+1 >= store.size {
+		return false, nil
 	}
-	offset = offset + int64(reservedTransactionStatusSize)
-	return TransactionalEntry{keyValuePairs: pairs, status: TransactionStatusFrom(transactionStatusBytes)}, offset, nil
+	//the remainder of the store is read once, every candidate offset is scanned in memory
+	remainder := make([]byte, store.size-offset-1)
+	if _, err := store.file.ReadAt(remainder, offset+1); err != nil {
+		return false, err
+	}
+	transactionHeaderSize := int(store.transactionHeaderSize())
+	for candidateOffset := 0; candidateOffset+transactionHeaderSize <= len(remainder); candidateOffset++ {
+		transactionEntrySize := int(TransactionalEntrySize(remainder[candidateOffset : candidateOffset+transactionHeaderSize]))
+		endOffset := candidateOffset +
+			transactionHeaderSize +
+			transactionEntrySize +
+			int(reservedTransactionStatusSize) +
+			int(reservedChecksumSize)
+
+		if endOffset > len(remainder) {
+			continue
+		}
+		if checksumMatches(remainder[candidateOffset:endOffset]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checksumMatches returns true if the checksum at the end of the transactional entry matches its contents
+func checksumMatches(bytes []byte) bool {
+	checksumOffset := len(bytes) - int(reservedChecksumSize)
+	return crc32.Checksum(bytes[:checksumOffset], crc32Table) == bigEndian.Uint32(bytes[checksumOffset:])
 }
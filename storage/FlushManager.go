@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"storage-engine-workshop/storage/memory"
+	"storage-engine-workshop/storage/sst"
+	"sync"
+)
+
+// FlushManager writes the immutable memTables to ssTables in background, oldest first, one at a time.
+// An immutable memTable remains readable until its ssTable is searchable, it is pending until the WAL offset is recorded as persisted.
+// A failed flush stops all the flushes, the error is returned by Err and by every later Schedule.
+type FlushManager struct {
+	ssTables            *sst.SSTables
+	maxPendingMemTables int
+	onFlush             func(walOffset int64)
+	pending             []pendingFlush
+	flushing            bool
+	err                 error
+	lock                sync.Mutex
+	condition           *sync.Cond
+}
+
+type pendingFlush struct {
+	memTable   *memory.MemTable
+	walOffset  int64
+	searchable bool
+}
+
+// NewFlushManager returns a FlushManager which calls onFlush with the WAL offset of every memTable persisted in an ssTable.
+// At least one memTable is allowed to be pending, a Schedule would otherwise block forever
+func NewFlushManager(ssTables *sst.SSTables, maxPendingMemTables int, onFlush func(walOffset int64)) *FlushManager {
+	if maxPendingMemTables < 1 {
+		maxPendingMemTables = 1
+	}
+	flushManager := &FlushManager{
+		ssTables:            ssTables,
+		maxPendingMemTables: maxPendingMemTables,
+		onFlush:             onFlush,
+	}
+	flushManager.condition = sync.NewCond(&flushManager.lock)
+	return flushManager
+}
+
+// Schedule queues the memTable, which contains the WAL contents up to the walOffset, to be flushed.
+// It blocks while the maximum number of memTables are pending, which stalls the writer until a flush finishes
+func (flushManager *FlushManager) Schedule(memTable *memory.MemTable, walOffset int64) error {
+	flushManager.lock.Lock()
+	defer flushManager.lock.Unlock()
+
+	for flushManager.err == nil && len(flushManager.pending) >= flushManager.maxPendingMemTables {
+		flushManager.condition.Wait()
+	}
+	if flushManager.err != nil {
+		return flushManager.err
+	}
+	flushManager.pending = append(flushManager.pending, pendingFlush{memTable: memTable, walOffset: walOffset})
+	if !flushManager.flushing {
+		flushManager.flushing = true
+		go flushManager.flushPending()
+	}
+	return nil
+}
+
+// MemTables returns the pending memTables whose ssTables are not yet searchable, newest first
+func (flushManager *FlushManager) MemTables() []*memory.MemTable {
+	flushManager.lock.Lock()
+	defer flushManager.lock.Unlock()
+
+	memTables := make([]*memory.MemTable, 0, len(flushManager.pending))
+	for index := len(flushManager.pending) - 1; index >= 0; index-- {
+		if !flushManager.pending[index].searchable {
+			memTables = append(memTables, flushManager.pending[index].memTable)
+		}
+	}
+	return memTables
+}
+
+// Err returns the error of the failed flush, if any
+func (flushManager *FlushManager) Err() error {
+	flushManager.lock.Lock()
+	defer flushManager.lock.Unlock()
+
+	return flushManager.err
+}
+
+// WaitForPendingFlushes blocks until all the scheduled memTables are flushed, or a flush fails
+func (flushManager *FlushManager) WaitForPendingFlushes() error {
+	flushManager.lock.Lock()
+	defer flushManager.lock.Unlock()
+
+	for flushManager.err == nil && len(flushManager.pending) > 0 {
+		flushManager.condition.Wait()
+	}
+	return flushManager.err
+}
+
+func (flushManager *FlushManager) flushPending() {
+	for {
+		flushManager.lock.Lock()
+		next := flushManager.pending[0]
+		flushManager.lock.Unlock()
+
+		//the memTable is not searched once its ssTable is, the oldest pending memTable is the one being flushed
+		markSearchable := func() {
+			flushManager.lock.Lock()
+			flushManager.pending[0].searchable = true
+			flushManager.lock.Unlock()
+		}
+		status := <-NewMemTableWriter(next.memTable, next.walOffset, flushManager.ssTables).OnSearchable(markSearchable).Write()
+		if status.Err() == nil {
+			flushManager.onFlush(next.walOffset)
+		}
+
+		flushManager.lock.Lock()
+		if status.Err() != nil {
+			flushManager.err, flushManager.flushing = status.Err(), false
+			flushManager.condition.Broadcast()
+			flushManager.lock.Unlock()
+			return
+		}
+		flushManager.pending = flushManager.pending[1:]
+		flushManager.condition.Broadcast()
+		if len(flushManager.pending) == 0 {
+			flushManager.flushing = false
+			flushManager.lock.Unlock()
+			return
+		}
+		flushManager.lock.Unlock()
+	}
+}
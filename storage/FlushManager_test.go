@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"os"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/memory"
+	"storage-engine-workshop/storage/sst"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFlushesScheduledMemTablesOldestFirst(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+	ssTables, _ := sst.NewSSTables(directory)
+
+	var flushedOffsets []int64
+	flushManager := NewFlushManager(ssTables, 2, func(walOffset int64) {
+		flushedOffsets = append(flushedOffsets, walOffset)
+	})
+	for count := 1; count <= 3; count++ {
+		memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk-"+strconv.Itoa(count))))
+		if err := flushManager.Schedule(memTable, int64(count)); err != nil {
+			t.Fatalf("Expected no error while scheduling a flush, received %v", err)
+		}
+		if pending := len(flushManager.MemTables()); pending > 2 {
+			t.Fatalf("Expected at most %v memTables pending to be flushed, received %v", 2, pending)
+		}
+	}
+	if err := flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected no error while flushing, received %v", err)
+	}
+	if len(flushManager.MemTables()) != 0 {
+		t.Fatalf("Expected no memTables pending to be flushed, received %v", len(flushManager.MemTables()))
+	}
+	if len(flushedOffsets) != 3 || flushedOffsets[0] != 1 || flushedOffsets[2] != 3 {
+		t.Fatalf("Expected the memTables to be flushed in the order %v, received %v", []int64{1, 2, 3}, flushedOffsets)
+	}
+	if getResult := ssTables.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Hard disk-3" {
+		t.Fatalf("Expected %v, received %v", "Hard disk-3", getResult.Value.AsString())
+	}
+}
+
+func TestStopsFlushingAfterAFailedFlush(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+	ssTables, _ := sst.NewSSTables(directory)
+
+	flushManager := NewFlushManager(ssTables, 2, func(walOffset int64) {})
+	_ = flushManager.Schedule(memory.NewMemTable(10, comparator.StringKeyComparator{}), 0)
+
+	if err := flushManager.WaitForPendingFlushes(); err == nil {
+		t.Fatalf("Expected an error while flushing an empty memTable, received none")
+	}
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	if err := flushManager.Schedule(memTable, 1); err == nil {
+		t.Fatalf("Expected an error while scheduling a flush after a failed flush, received none")
+	}
+	if flushManager.Err() == nil {
+		t.Fatalf("Expected the error of the failed flush, received none")
+	}
+}
+
+func TestStopsReturningAMemTableOnceItsSSTableIsSearchable(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+	ssTables, _ := sst.NewSSTables(directory)
+
+	memTablesWhilePersisting := -1
+	var flushManager *FlushManager
+	flushManager = NewFlushManager(ssTables, 2, func(walOffset int64) {
+		memTablesWhilePersisting = len(flushManager.MemTables())
+	})
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	_ = flushManager.Schedule(memTable, 1)
+
+	if err := flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected no error while flushing, received %v", err)
+	}
+	if memTablesWhilePersisting != 0 {
+		t.Fatalf("Expected no memTables to be returned once the ssTable is searchable, received %v", memTablesWhilePersisting)
+	}
+}
+
+func TestAllowsOneMemTableToBePendingWithAMaximumBelowOne(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+	ssTables, _ := sst.NewSSTables(directory)
+
+	flushManager := NewFlushManager(ssTables, 0, func(walOffset int64) {})
+	scheduled := make(chan error)
+	go func() {
+		memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+		scheduled <- flushManager.Schedule(memTable, 1)
+	}()
+
+	select {
+	case err := <-scheduled:
+		if err != nil {
+			t.Fatalf("Expected no error while scheduling a flush, received %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the flush to be scheduled with a maximum of 0 pending memTables, but Schedule blocked")
+	}
+	if err := flushManager.WaitForPendingFlushes(); err != nil {
+		t.Fatalf("Expected no error while flushing, received %v", err)
+	}
+}
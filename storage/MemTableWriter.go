@@ -15,19 +15,30 @@ type MemTableWriteStatus struct {
 	err    error
 }
 
+// MemTableWriter writes a memTable to an ssTable. walOffset is the WAL offset up to which the memTable contains the data,
+// it is recorded as persisted once the ssTable is searchable.
 type MemTableWriter struct {
-	memTable *memory.MemTable
-	ssTables *sst.SSTables
-	ssTable  *sst.SSTable
+	memTable     *memory.MemTable
+	walOffset    int64
+	ssTables     *sst.SSTables
+	ssTable      *sst.SSTable
+	onSearchable func()
 }
 
-func NewMemTableWriter(memTable *memory.MemTable, ssTables *sst.SSTables) *MemTableWriter {
+func NewMemTableWriter(memTable *memory.MemTable, walOffset int64, ssTables *sst.SSTables) *MemTableWriter {
 	return &MemTableWriter{
-		memTable: memTable,
-		ssTables: ssTables,
+		memTable:  memTable,
+		walOffset: walOffset,
+		ssTables:  ssTables,
 	}
 }
 
+// OnSearchable registers the callback which runs as soon as the ssTable is searchable, before the WAL offset is recorded as persisted
+func (memTableWriter *MemTableWriter) OnSearchable(callback func()) *MemTableWriter {
+	memTableWriter.onSearchable = callback
+	return memTableWriter
+}
+
 func (memTableWriter *MemTableWriter) Write() <-chan MemTableWriteStatus {
 	response := make(chan MemTableWriteStatus)
 
@@ -41,12 +52,26 @@ func (memTableWriter *MemTableWriter) Write() <-chan MemTableWriteStatus {
 			writeErrorToChannel(err, response)
 			return
 		}
-		memTableWriter.ssTables.AllowSearchIn(memTableWriter.ssTable)
+		if err := memTableWriter.ssTables.AllowSearchIn(memTableWriter.ssTable); err != nil {
+			writeErrorToChannel(err, response)
+			return
+		}
+		if memTableWriter.onSearchable != nil {
+			memTableWriter.onSearchable()
+		}
+		if err := memTableWriter.ssTables.SetPersisted(memTableWriter.walOffset, memTableWriter.memTable.LastSequence()); err != nil {
+			writeErrorToChannel(err, response)
+			return
+		}
 		writeSuccessToChannel(response)
 	}()
 	return response
 }
 
+func (status MemTableWriteStatus) Err() error {
+	return status.err
+}
+
 func (memTableWriter *MemTableWriter) mutateWithSsTable() error {
 	ssTable, err := memTableWriter.ssTables.NewSSTable(memTableWriter.memTable)
 	if err != nil {
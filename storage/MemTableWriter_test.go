@@ -29,7 +29,7 @@ func TestMemTableWriterWithSuccessAsStatus(t *testing.T) {
 	defer os.RemoveAll(directory)
 	ssTables, _ := sst.NewSSTables(directory)
 
-	memTableWriter := NewMemTableWriter(memTable, ssTables)
+	memTableWriter := NewMemTableWriter(memTable, 0, ssTables)
 	statusChannel := memTableWriter.Write()
 	status := <-statusChannel
 
@@ -45,7 +45,7 @@ func TestMemTableWriterWithFailureAsStatus(t *testing.T) {
 	defer os.RemoveAll(directory)
 	ssTables, _ := sst.NewSSTables(directory)
 
-	memTableWriter := NewMemTableWriter(emptyMemTable, ssTables)
+	memTableWriter := NewMemTableWriter(emptyMemTable, 0, ssTables)
 	statusChannel := memTableWriter.Write()
 	status := <-statusChannel
 
@@ -53,3 +53,41 @@ func TestMemTableWriterWithFailureAsStatus(t *testing.T) {
 		t.Fatalf("Expected memtable flush status to be FAILURE but received %v", status.status)
 	}
 }
+
+func TestMemTableWriterRecordsPersistedWALOffset(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+	ssTables, _ := sst.NewSSTables(directory)
+
+	status := <-NewMemTableWriter(memTable, 128, ssTables).Write()
+	if status.Err() != nil {
+		t.Fatalf("Expected no error while writing memtable, received %v", status.Err())
+	}
+	if ssTables.PersistedWALOffset() != 128 {
+		t.Fatalf("Expected persisted WAL offset to be %v, received %v", 128, ssTables.PersistedWALOffset())
+	}
+}
+
+func TestMemTableWriterRunsTheCallbackOnceTheSSTableIsSearchable(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+	ssTables, _ := sst.NewSSTables(directory)
+
+	var getResult model.GetResult
+	status := <-NewMemTableWriter(memTable, 0, ssTables).OnSearchable(func() {
+		getResult = ssTables.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{})
+	}).Write()
+
+	if status.status != SUCCESS {
+		t.Fatalf("Expected memtable flush status to be SUCCESS but received %v", status)
+	}
+	if getResult.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected the ssTable to be searchable in the callback with %v, received %v", "Hard disk", getResult.Value.AsString())
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// BlockKey identifies a block by the file id of its ssTable and its offset in the file
+type BlockKey struct {
+	FileId int
+	Offset int64
+}
+
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheEntry struct {
+	key    BlockKey
+	block  interface{}
+	charge int64
+}
+
+// BlockCache is a size-bounded LRU cache of blocks shared by all the ssTables, safe for concurrent use.
+// Each block is charged with its size in bytes, the least recently used blocks are evicted once the capacity is exceeded.
+type BlockCache struct {
+	capacityBytes int64
+	usedBytes     int64
+	entries       map[BlockKey]*list.Element
+	recency       *list.List
+	hits          uint64
+	misses        uint64
+	lock          sync.Mutex
+}
+
+func NewBlockCache(capacityBytes int64) *BlockCache {
+	return &BlockCache{
+		capacityBytes: capacityBytes,
+		entries:       make(map[BlockKey]*list.Element),
+		recency:       list.New(),
+	}
+}
+
+func (blockCache *BlockCache) Get(key BlockKey) (interface{}, bool) {
+	blockCache.lock.Lock()
+	defer blockCache.lock.Unlock()
+
+	element, ok := blockCache.entries[key]
+	if !ok {
+		atomic.AddUint64(&blockCache.misses, 1)
+		return nil, false
+	}
+	atomic.AddUint64(&blockCache.hits, 1)
+	blockCache.recency.MoveToFront(element)
+	return element.Value.(*cacheEntry).block, true
+}
+
+// Put caches the block, a block larger than the capacity is not cached
+func (blockCache *BlockCache) Put(key BlockKey, block interface{}, charge int64) {
+	blockCache.lock.Lock()
+	defer blockCache.lock.Unlock()
+
+	if charge > blockCache.capacityBytes {
+		return
+	}
+	if element, ok := blockCache.entries[key]; ok {
+		blockCache.remove(element)
+	}
+	blockCache.entries[key] = blockCache.recency.PushFront(&cacheEntry{key: key, block: block, charge: charge})
+	blockCache.usedBytes = blockCache.usedBytes + charge
+
+	for blockCache.usedBytes > blockCache.capacityBytes {
+		blockCache.remove(blockCache.recency.Back())
+	}
+}
+
+func (blockCache *BlockCache) Stats() Stats {
+	return Stats{
+		Hits:   atomic.LoadUint64(&blockCache.hits),
+		Misses: atomic.LoadUint64(&blockCache.misses),
+	}
+}
+
+func (blockCache *BlockCache) remove(element *list.Element) {
+	entry := blockCache.recency.Remove(element).(*cacheEntry)
+	delete(blockCache.entries, entry.key)
+	blockCache.usedBytes = blockCache.usedBytes - entry.charge
+}
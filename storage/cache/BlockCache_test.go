@@ -0,0 +1,51 @@
+package cache
+
+import "testing"
+
+func TestGetsACachedBlock(t *testing.T) {
+	blockCache := NewBlockCache(1024)
+	blockCache.Put(BlockKey{FileId: 1, Offset: 0}, "block", 100)
+
+	block, ok := blockCache.Get(BlockKey{FileId: 1, Offset: 0})
+	if !ok || block.(string) != "block" {
+		t.Fatalf("Expected the block to be cached, received %v", block)
+	}
+	if _, ok := blockCache.Get(BlockKey{FileId: 2, Offset: 0}); ok {
+		t.Fatalf("Expected the block of another file not to be cached")
+	}
+	if stats := blockCache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("Expected 1 hit and 1 miss, received %v hits and %v misses", stats.Hits, stats.Misses)
+	}
+}
+
+func TestEvictsTheLeastRecentlyUsedBlockOnceTheCapacityIsExceeded(t *testing.T) {
+	blockCache := NewBlockCache(300)
+	blockCache.Put(BlockKey{FileId: 1, Offset: 0}, "first", 100)
+	blockCache.Put(BlockKey{FileId: 1, Offset: 100}, "second", 100)
+	blockCache.Put(BlockKey{FileId: 1, Offset: 200}, "third", 100)
+
+	_, _ = blockCache.Get(BlockKey{FileId: 1, Offset: 0})
+	blockCache.Put(BlockKey{FileId: 1, Offset: 300}, "fourth", 100)
+
+	if _, ok := blockCache.Get(BlockKey{FileId: 1, Offset: 100}); ok {
+		t.Fatalf("Expected the least recently used block to be evicted")
+	}
+	for _, offset := range []int64{0, 200, 300} {
+		if _, ok := blockCache.Get(BlockKey{FileId: 1, Offset: offset}); !ok {
+			t.Fatalf("Expected the block at offset %v to be cached", offset)
+		}
+	}
+}
+
+func TestDoesNotCacheABlockLargerThanTheCapacity(t *testing.T) {
+	blockCache := NewBlockCache(100)
+	blockCache.Put(BlockKey{FileId: 1, Offset: 0}, "first", 50)
+	blockCache.Put(BlockKey{FileId: 1, Offset: 50}, "large", 200)
+
+	if _, ok := blockCache.Get(BlockKey{FileId: 1, Offset: 50}); ok {
+		t.Fatalf("Expected a block larger than the capacity not to be cached")
+	}
+	if _, ok := blockCache.Get(BlockKey{FileId: 1, Offset: 0}); !ok {
+		t.Fatalf("Expected the cached block to remain cached")
+	}
+}
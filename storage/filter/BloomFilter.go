@@ -54,8 +54,7 @@ func (bloomFilter *BloomFilter) Put(key model.Slice) error {
 		if int(bytePosition) >= bloomFilter.store.Size() {
 			return errors.New(fmt.Sprintf("bytePosition %v is greater than bloom filter file size for indices[index] %v", bytePosition, indices[index]))
 		}
-		//Assignment:Bloom filter:1:set bit
-		fmt.Println(mask)
+		bloomFilter.store.SetBit(bytePosition, mask)
 	}
 	return nil
 }
@@ -68,17 +67,24 @@ func (bloomFilter *BloomFilter) Has(key model.Slice) bool {
 		if int(bytePosition) >= bloomFilter.store.Size() {
 			return false
 		}
-		//Assignment:Bloom filter:2:check the bit
-		fmt.Println(mask)
-		if false {
+		if bloomFilter.store.GetByte(bytePosition)&mask == 0 {
 			return false
 		}
 	}
 	return true
 }
 
-func (bloomFilter *BloomFilter) Close() {
-	bloomFilter.store.Close()
+// Sync makes the keys put in the bloom filter durable, they are otherwise written to the file only when it is closed
+func (bloomFilter *BloomFilter) Sync() error {
+	return bloomFilter.store.Sync()
+}
+
+func (bloomFilter *BloomFilter) FileName() string {
+	return bloomFilter.fileName
+}
+
+func (bloomFilter *BloomFilter) Close() error {
+	return bloomFilter.store.Close()
 }
 
 func (bloomFilter *BloomFilter) bitPositionInByte(keyIndex uint64) (uint64, byte) {
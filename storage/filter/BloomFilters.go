@@ -10,12 +10,15 @@ import (
 	"storage-engine-workshop/db/model"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type BloomFilters struct {
 	directory         string
 	falsePositiveRate float64
 	filters           []*BloomFilter
+	filterByPrefix    map[string]*BloomFilter
+	lock              sync.RWMutex
 }
 
 type BloomFilterOptions struct {
@@ -39,7 +42,11 @@ func NewBloomFilters(directory string, falsePositiveRate float64) (*BloomFilters
 			return nil, err
 		}
 	}
-	filters := &BloomFilters{directory: subDirectory, falsePositiveRate: falsePositiveRate}
+	filters := &BloomFilters{
+		directory:         subDirectory,
+		falsePositiveRate: falsePositiveRate,
+		filterByPrefix:    make(map[string]*BloomFilter),
+	}
 	if err := filters.init(); err != nil {
 		return nil, err
 	} else {
@@ -52,22 +59,69 @@ func (bloomFilters *BloomFilters) NewBloomFilter(options BloomFilterOptions) (*B
 		return nil, errors.New("bloom filter needs a prefix which will be a part of its name")
 	}
 
+	bloomFilters.lock.Lock()
+	defer bloomFilters.lock.Unlock()
+
 	fileName := path.Join(bloomFilters.directory, bloomFilters.bloomFilterFileName(options))
 	if filter, err := newBloomFilter(minCapacityToEnsureZeroFalseNegatives(options), options.DataSize, bloomFilters.falsePositiveRate, fileName); err != nil {
 		return nil, err
 	} else {
 		bloomFilters.filters = append(bloomFilters.filters, filter)
+		bloomFilters.filterByPrefix[options.FileNamePrefix] = filter
 		return filter, nil
 	}
 }
 
-func (bloomFilters *BloomFilters) Close() {
+func (bloomFilters *BloomFilters) BloomFilterWith(fileNamePrefix string) (*BloomFilter, bool) {
+	bloomFilters.lock.RLock()
+	defer bloomFilters.lock.RUnlock()
+
+	filter, ok := bloomFilters.filterByPrefix[fileNamePrefix]
+	return filter, ok
+}
+
+func (bloomFilters *BloomFilters) Delete(bloomFilter *BloomFilter) error {
+	bloomFilters.lock.Lock()
+	defer bloomFilters.lock.Unlock()
+
+	for index, filter := range bloomFilters.filters {
+		if filter == bloomFilter {
+			bloomFilters.filters = append(bloomFilters.filters[:index], bloomFilters.filters[index+1:]...)
+			break
+		}
+	}
+	for prefix, filter := range bloomFilters.filterByPrefix {
+		if filter == bloomFilter {
+			delete(bloomFilters.filterByPrefix, prefix)
+		}
+	}
+	_ = bloomFilter.Close()
+	return os.Remove(bloomFilter.fileName)
+}
+
+// SyncDirectory makes the entries of the bloom filter files created in the directory durable
+func (bloomFilters *BloomFilters) SyncDirectory() error {
+	return syncDirectory(bloomFilters.directory)
+}
+
+// Close closes all the bloom filters and returns the first error
+func (bloomFilters *BloomFilters) Close() error {
+	bloomFilters.lock.RLock()
+	defer bloomFilters.lock.RUnlock()
+
+	var err error
 	for _, bloomFilter := range bloomFilters.filters {
-		bloomFilter.Close()
+		if closeErr := bloomFilter.Close(); err == nil {
+			err = closeErr
+		}
 	}
+	return err
 }
 
 func (bloomFilters *BloomFilters) Has(key model.Slice) bool {
+	bloomFilters.lock.RLock()
+	defer bloomFilters.lock.RUnlock()
+
 	for _, bloomFilter := range bloomFilters.filters {
 		if bloomFilter.Has(key) {
 			return true
@@ -122,3 +176,12 @@ func minCapacityToEnsureZeroFalseNegatives(options BloomFilterOptions) int {
 	}
 	return options.Capacity
 }
+
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -77,6 +77,35 @@ func TestAddsAKeyWithBloomFilterAndChecksForItsPositiveExistenceSimulatingAResta
 	}
 }
 
+func TestSyncsTheKeysOfABloomFilterToItsFile(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	bloomFilters, _ := NewBloomFilters(directory, 0.001)
+	bloomFilter, _ := bloomFilters.NewBloomFilter(BloomFilterOptions{
+		Capacity:       1,
+		FileNamePrefix: "1",
+	})
+	_ = bloomFilter.Put(model.NewSlice([]byte("Company")))
+
+	if err := bloomFilter.Sync(); err != nil {
+		t.Fatalf("Expected no error while syncing the bloom filter, received %v", err)
+	}
+	if err := bloomFilters.SyncDirectory(); err != nil {
+		t.Fatalf("Expected no error while syncing the bloom filter directory, received %v", err)
+	}
+	contents, _ := ioutil.ReadFile(bloomFilter.FileName())
+	bitsSet := 0
+	for _, aByte := range contents {
+		if aByte != 0 {
+			bitsSet = bitsSet + 1
+		}
+	}
+	if bitsSet == 0 {
+		t.Fatalf("Expected the bits of the key to be set in the bloom filter file")
+	}
+}
+
 func TestAddsAKeyWithMultipleBloomFiltersAndChecksForItsPositiveExistenceSimulatingARestart(t *testing.T) {
 	directory := tempDirectory()
 	defer os.RemoveAll(directory)
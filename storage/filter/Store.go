@@ -2,7 +2,6 @@ package filter
 
 import (
 	"github.com/edsrzf/mmap-go"
-	"log"
 	"os"
 )
 
@@ -48,9 +47,18 @@ func (store *Store) Size() int {
 	return len(store.memoryMappedRegion)
 }
 
-func (store *Store) Close() {
-	err := store.file.Close()
-	if err != nil {
-		log.Default().Println("Error while closing the file " + store.file.Name())
+// Sync writes the memory mapped region back to the file and syncs the file
+func (store *Store) Sync() error {
+	if err := store.memoryMappedRegion.Flush(); err != nil {
+		return err
+	}
+	return store.file.Sync()
+}
+
+func (store *Store) Close() error {
+	err := store.memoryMappedRegion.Unmap()
+	if closeErr := store.file.Close(); err == nil {
+		err = closeErr
 	}
+	return err
 }
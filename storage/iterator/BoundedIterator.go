@@ -0,0 +1,64 @@
+package iterator
+
+import (
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+)
+
+// BoundedIterator stops at the first key greater than or equal to the (exclusive) end key
+type BoundedIterator struct {
+	iterator      Iterator
+	end           model.Slice
+	keyComparator comparator.KeyComparator
+}
+
+func NewBoundedIterator(iterator Iterator, end model.Slice, keyComparator comparator.KeyComparator) *BoundedIterator {
+	return &BoundedIterator{
+		iterator:      iterator,
+		end:           end,
+		keyComparator: keyComparator,
+	}
+}
+
+func (boundedIterator *BoundedIterator) Seek(key model.Slice) {
+	boundedIterator.iterator.Seek(key)
+}
+
+func (boundedIterator *BoundedIterator) Next() {
+	if boundedIterator.IsValid() {
+		boundedIterator.iterator.Next()
+	}
+}
+
+func (boundedIterator *BoundedIterator) IsValid() bool {
+	return boundedIterator.iterator.IsValid() &&
+		boundedIterator.keyComparator.Compare(boundedIterator.iterator.Key(), boundedIterator.end) < 0
+}
+
+func (boundedIterator *BoundedIterator) Key() model.Slice {
+	return boundedIterator.iterator.Key()
+}
+
+func (boundedIterator *BoundedIterator) Value() model.Slice {
+	return boundedIterator.iterator.Value()
+}
+
+func (boundedIterator *BoundedIterator) IsDeleted() bool {
+	return boundedIterator.iterator.IsDeleted()
+}
+
+func (boundedIterator *BoundedIterator) IsValuePointer() bool {
+	return boundedIterator.iterator.IsValuePointer()
+}
+
+func (boundedIterator *BoundedIterator) Sequence() uint64 {
+	return boundedIterator.iterator.Sequence()
+}
+
+func (boundedIterator *BoundedIterator) Err() error {
+	return boundedIterator.iterator.Err()
+}
+
+func (boundedIterator *BoundedIterator) Close() {
+	boundedIterator.iterator.Close()
+}
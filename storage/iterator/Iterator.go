@@ -0,0 +1,21 @@
+package iterator
+
+import "storage-engine-workshop/db/model"
+
+// Iterator walks key/value pairs in the order defined by a comparator.KeyComparator.
+// Seek positions the iterator at the first key greater than or equal to the given key.
+// IsValuePointer returns true if the Value is an encoded pointer to the value in the value log.
+// Sequence returns the sequence of the write of the current version of the key.
+// Err returns the error which made the iterator invalid before reaching its end, an invalid iterator without an error is exhausted.
+type Iterator interface {
+	Seek(key model.Slice)
+	Next()
+	IsValid() bool
+	Key() model.Slice
+	Value() model.Slice
+	IsDeleted() bool
+	IsValuePointer() bool
+	Sequence() uint64
+	Err() error
+	Close()
+}
@@ -0,0 +1,148 @@
+package iterator
+
+import (
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+)
+
+// MergedIterator merges iterators which are ordered newest-first.
+// When the same key is present in multiple iterators, the newest version wins and the older versions are hidden.
+// Keys whose newest version is a tombstone are skipped, unless the iterator is created to keep tombstones.
+// An iterator created to keep versions returns every version of every key, ordered by key and newest sequence first.
+// The iterator becomes invalid once any of the iterators fails, an older version of a key could otherwise be returned in place of the newest.
+type MergedIterator struct {
+	iterators      []Iterator
+	current        int
+	keyComparator  comparator.KeyComparator
+	keepTombstones bool
+	keepVersions   bool
+}
+
+func NewMergedIterator(iterators []Iterator, keyComparator comparator.KeyComparator) *MergedIterator {
+	return &MergedIterator{
+		iterators:     iterators,
+		current:       -1,
+		keyComparator: keyComparator,
+	}
+}
+
+// NewMergedIteratorKeepingTombstones returns the newest version of each key, including tombstones. Used by compaction
+func NewMergedIteratorKeepingTombstones(iterators []Iterator, keyComparator comparator.KeyComparator) *MergedIterator {
+	mergedIterator := NewMergedIterator(iterators, keyComparator)
+	mergedIterator.keepTombstones = true
+	return mergedIterator
+}
+
+// NewMergedIteratorKeepingVersions returns all the versions of each key, including tombstones.
+// Used by compaction, with iterators which return all the versions of each key
+func NewMergedIteratorKeepingVersions(iterators []Iterator, keyComparator comparator.KeyComparator) *MergedIterator {
+	mergedIterator := NewMergedIteratorKeepingTombstones(iterators, keyComparator)
+	mergedIterator.keepVersions = true
+	return mergedIterator
+}
+
+func (mergedIterator *MergedIterator) Seek(key model.Slice) {
+	for _, iterator := range mergedIterator.iterators {
+		iterator.Seek(key)
+	}
+	mergedIterator.positionAtLiveKey()
+}
+
+func (mergedIterator *MergedIterator) Next() {
+	if !mergedIterator.IsValid() {
+		return
+	}
+	if mergedIterator.keepVersions {
+		mergedIterator.iterators[mergedIterator.current].Next()
+	} else {
+		mergedIterator.skipCurrentKey()
+	}
+	mergedIterator.positionAtLiveKey()
+}
+
+func (mergedIterator *MergedIterator) IsValid() bool {
+	return mergedIterator.current != -1
+}
+
+func (mergedIterator *MergedIterator) Key() model.Slice {
+	return mergedIterator.iterators[mergedIterator.current].Key()
+}
+
+func (mergedIterator *MergedIterator) Value() model.Slice {
+	return mergedIterator.iterators[mergedIterator.current].Value()
+}
+
+func (mergedIterator *MergedIterator) IsDeleted() bool {
+	if !mergedIterator.keepTombstones {
+		return false
+	}
+	return mergedIterator.iterators[mergedIterator.current].IsDeleted()
+}
+
+func (mergedIterator *MergedIterator) IsValuePointer() bool {
+	return mergedIterator.iterators[mergedIterator.current].IsValuePointer()
+}
+
+func (mergedIterator *MergedIterator) Sequence() uint64 {
+	return mergedIterator.iterators[mergedIterator.current].Sequence()
+}
+
+// Err returns the error of the first of the iterators which failed
+func (mergedIterator *MergedIterator) Err() error {
+	for _, iterator := range mergedIterator.iterators {
+		if err := iterator.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mergedIterator *MergedIterator) Close() {
+	for _, iterator := range mergedIterator.iterators {
+		iterator.Close()
+	}
+	mergedIterator.current = -1
+}
+
+func (mergedIterator *MergedIterator) positionAtLiveKey() {
+	for {
+		if mergedIterator.Err() != nil {
+			mergedIterator.current = -1
+			return
+		}
+		mergedIterator.current = mergedIterator.smallest()
+		if !mergedIterator.IsValid() || mergedIterator.keepTombstones || !mergedIterator.iterators[mergedIterator.current].IsDeleted() {
+			return
+		}
+		mergedIterator.skipCurrentKey()
+	}
+}
+
+// smallest returns the index of the iterator positioned at the smallest key, preferring the newest iterator on ties.
+// If versions are kept, ties are broken by the newest sequence first
+func (mergedIterator *MergedIterator) smallest() int {
+	smallest := -1
+	for index, iterator := range mergedIterator.iterators {
+		if !iterator.IsValid() {
+			continue
+		}
+		if smallest == -1 {
+			smallest = index
+			continue
+		}
+		comparison := mergedIterator.keyComparator.Compare(iterator.Key(), mergedIterator.iterators[smallest].Key())
+		if comparison < 0 || (comparison == 0 && mergedIterator.keepVersions && iterator.Sequence() > mergedIterator.iterators[smallest].Sequence()) {
+			smallest = index
+		}
+	}
+	return smallest
+}
+
+func (mergedIterator *MergedIterator) skipCurrentKey() {
+	key := mergedIterator.Key()
+	for _, iterator := range mergedIterator.iterators {
+		if iterator.IsValid() && mergedIterator.keyComparator.Compare(iterator.Key(), key) == 0 {
+			iterator.Next()
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package iterator
+
+import (
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/memory"
+	"testing"
+)
+
+func TestMergesIteratorsAndHidesOlderVersions(t *testing.T) {
+	newer := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	newer.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk drive")))
+	newer.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+
+	older := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	older.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	older.Put(model.NewSlice([]byte("PMEM")), model.NewSlice([]byte("Persistent memory")))
+
+	iterator := NewMergedIterator([]Iterator{newer.NewIterator(), older.NewIterator()}, comparator.StringKeyComparator{})
+	iterator.Seek(model.NilSlice())
+
+	expected := []model.KeyValuePair{
+		{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk drive"))},
+		{Key: model.NewSlice([]byte("PMEM")), Value: model.NewSlice([]byte("Persistent memory"))},
+		{Key: model.NewSlice([]byte("SDD")), Value: model.NewSlice([]byte("Solid state"))},
+	}
+	for _, keyValuePair := range expected {
+		if iterator.Key().AsString() != keyValuePair.Key.AsString() {
+			t.Fatalf("Expected key to be %v, received %v", keyValuePair.Key.AsString(), iterator.Key().AsString())
+		}
+		if iterator.Value().AsString() != keyValuePair.Value.AsString() {
+			t.Fatalf("Expected value to be %v, received %v", keyValuePair.Value.AsString(), iterator.Value().AsString())
+		}
+		iterator.Next()
+	}
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
+
+func TestMergesIteratorsAndSkipsDeletedKeys(t *testing.T) {
+	newer := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	newer.Delete(model.NewSlice([]byte("HDD")))
+
+	older := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	older.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	older.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+
+	iterator := NewMergedIterator([]Iterator{newer.NewIterator(), older.NewIterator()}, comparator.StringKeyComparator{})
+	iterator.Seek(model.NilSlice())
+
+	if iterator.Key().AsString() != "SDD" {
+		t.Fatalf("Expected key to be %v, received %v", "SDD", iterator.Key().AsString())
+	}
+	iterator.Next()
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
+
+func TestMergesIteratorsAndKeepsDeletedKeys(t *testing.T) {
+	newer := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	newer.Delete(model.NewSlice([]byte("HDD")))
+
+	older := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	older.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	older.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+
+	iterator := NewMergedIteratorKeepingTombstones([]Iterator{newer.NewIterator(), older.NewIterator()}, comparator.StringKeyComparator{})
+	iterator.Seek(model.NilSlice())
+
+	if iterator.Key().AsString() != "HDD" || !iterator.IsDeleted() {
+		t.Fatalf("Expected key %v to be deleted, received key %v with deleted %v", "HDD", iterator.Key().AsString(), iterator.IsDeleted())
+	}
+	iterator.Next()
+	if iterator.Key().AsString() != "SDD" || iterator.IsDeleted() {
+		t.Fatalf("Expected key %v to be live, received key %v with deleted %v", "SDD", iterator.Key().AsString(), iterator.IsDeleted())
+	}
+	iterator.Next()
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
+
+func TestBoundedIteratorStopsBeforeTheEndKey(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	memTable.Put(model.NewSlice([]byte("PMEM")), model.NewSlice([]byte("Persistent memory")))
+	memTable.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+
+	iterator := NewBoundedIterator(memTable.NewIterator(), model.NewSlice([]byte("SDD")), comparator.StringKeyComparator{})
+	iterator.Seek(model.NewSlice([]byte("I")))
+
+	if iterator.Key().AsString() != "PMEM" {
+		t.Fatalf("Expected key to be %v, received %v", "PMEM", iterator.Key().AsString())
+	}
+	iterator.Next()
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
+
+func TestMergesIteratorsKeepingVersionsNewestFirst(t *testing.T) {
+	newer := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	newer.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk drive")), Sequence: 3})
+
+	older := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	older.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Deleted: true, Sequence: 2})
+	older.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("SDD")), Value: model.NewSlice([]byte("Solid state")), Sequence: 1})
+
+	iterator := NewMergedIteratorKeepingVersions([]Iterator{older.NewIterator(), newer.NewIterator()}, comparator.StringKeyComparator{})
+	iterator.Seek(model.NilSlice())
+
+	expected := []model.KeyValuePair{
+		{Key: model.NewSlice([]byte("HDD")), Sequence: 3},
+		{Key: model.NewSlice([]byte("HDD")), Deleted: true, Sequence: 2},
+		{Key: model.NewSlice([]byte("SDD")), Sequence: 1},
+	}
+	for _, keyValuePair := range expected {
+		if !iterator.IsValid() || iterator.Sequence() != keyValuePair.Sequence {
+			t.Fatalf("Expected a version at sequence %v, received valid %v", keyValuePair.Sequence, iterator.IsValid())
+		}
+		if iterator.Key().AsString() != keyValuePair.Key.AsString() {
+			t.Fatalf("Expected key to be %v, received %v", keyValuePair.Key.AsString(), iterator.Key().AsString())
+		}
+		if iterator.IsDeleted() != keyValuePair.Deleted {
+			t.Fatalf("Expected deleted to be %v, received %v", keyValuePair.Deleted, iterator.IsDeleted())
+		}
+		iterator.Next()
+	}
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
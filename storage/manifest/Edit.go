@@ -0,0 +1,162 @@
+package manifest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	tagAddTable           byte = 1
+	tagRemoveTable        byte = 2
+	tagNextFileId         byte = 3
+	tagPersistedWALOffset byte = 4
+	tagLastSequence       byte = 5
+	tagAddVersionedTable  byte = 6
+)
+
+// TableEntry is a live ssTable, Version is the format version of the ssTable or 0 if it was recorded without one
+type TableEntry struct {
+	FileId  int
+	Level   int
+	Version int
+}
+
+// Edit is a set of changes which is recorded in the manifest as a single record, it is applied atomically
+type Edit struct {
+	addedTables        []TableEntry
+	removedTables      []int
+	nextFileId         int
+	persistedWALOffset int64
+	lastSequence       uint64
+}
+
+func NewEdit() *Edit {
+	return &Edit{nextFileId: -1, persistedWALOffset: -1}
+}
+
+// AddTable records the ssTable as live along with its format version, 0 if the version is not known
+func (edit *Edit) AddTable(fileId int, level int, version int) *Edit {
+	edit.addedTables = append(edit.addedTables, TableEntry{FileId: fileId, Level: level, Version: version})
+	return edit
+}
+
+func (edit *Edit) RemoveTable(fileId int) *Edit {
+	edit.removedTables = append(edit.removedTables, fileId)
+	return edit
+}
+
+func (edit *Edit) WithNextFileId(nextFileId int) *Edit {
+	edit.nextFileId = nextFileId
+	return edit
+}
+
+func (edit *Edit) WithPersistedWALOffset(offset int64) *Edit {
+	edit.persistedWALOffset = offset
+	return edit
+}
+
+// WithLastSequence records the sequence of the newest write persisted in ssTables, 0 leaves it unchanged
+func (edit *Edit) WithLastSequence(sequence uint64) *Edit {
+	edit.lastSequence = sequence
+	return edit
+}
+
+func (edit *Edit) marshal() []byte {
+	var bytes []byte
+	buffer := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(value uint64) {
+		size := binary.PutUvarint(buffer, value)
+		bytes = append(bytes, buffer[:size]...)
+	}
+	for _, table := range edit.addedTables {
+		if table.Version == 0 {
+			bytes = append(bytes, tagAddTable)
+		} else {
+			bytes = append(bytes, tagAddVersionedTable)
+		}
+		putUvarint(uint64(table.FileId))
+		putUvarint(uint64(table.Level))
+		if table.Version != 0 {
+			putUvarint(uint64(table.Version))
+		}
+	}
+	for _, fileId := range edit.removedTables {
+		bytes = append(bytes, tagRemoveTable)
+		putUvarint(uint64(fileId))
+	}
+	if edit.nextFileId >= 0 {
+		bytes = append(bytes, tagNextFileId)
+		putUvarint(uint64(edit.nextFileId))
+	}
+	if edit.persistedWALOffset >= 0 {
+		bytes = append(bytes, tagPersistedWALOffset)
+		putUvarint(uint64(edit.persistedWALOffset))
+	}
+	if edit.lastSequence > 0 {
+		bytes = append(bytes, tagLastSequence)
+		putUvarint(edit.lastSequence)
+	}
+	return bytes
+}
+
+func unmarshalEdit(bytes []byte) (*Edit, error) {
+	edit := NewEdit()
+	readUvarint := func() (uint64, error) {
+		value, size := binary.Uvarint(bytes)
+		if size <= 0 {
+			return 0, errors.New("malformed varint in manifest edit")
+		}
+		bytes = bytes[size:]
+		return value, nil
+	}
+	for len(bytes) > 0 {
+		tag := bytes[0]
+		bytes = bytes[1:]
+		switch tag {
+		case tagAddTable, tagAddVersionedTable:
+			fileId, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			level, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			var version uint64
+			if tag == tagAddVersionedTable {
+				if version, err = readUvarint(); err != nil {
+					return nil, err
+				}
+			}
+			edit.AddTable(int(fileId), int(level), int(version))
+		case tagRemoveTable:
+			fileId, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			edit.RemoveTable(int(fileId))
+		case tagNextFileId:
+			nextFileId, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			edit.WithNextFileId(int(nextFileId))
+		case tagPersistedWALOffset:
+			offset, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			edit.WithPersistedWALOffset(int64(offset))
+		case tagLastSequence:
+			sequence, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			edit.WithLastSequence(sequence)
+		default:
+			return nil, errors.New(fmt.Sprintf("unknown tag %v in manifest edit", tag))
+		}
+	}
+	return edit, nil
+}
@@ -0,0 +1,240 @@
+package manifest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+)
+
+const (
+	fileName              = "MANIFEST"
+	temporaryFileName     = "MANIFEST.tmp"
+	magic          uint32 = 0x4d414e46
+	currentVersion uint16 = 2
+	headerSize            = 6
+	recordHeaderSize      = 8
+)
+
+var (
+	bigEndian  = binary.BigEndian
+	crc32Table = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Manifest is an append-only log of edits describing the live ssTables, the next file id,
+// the WAL offset up to which the data is persisted in ssTables and the sequence of the newest write persisted in ssTables.
+// Layout: 4 bytes magic | 2 bytes version | records, where each record is
+// 4 bytes size | 4 bytes crc32 of the edit | edit.
+// Every time the manifest is opened, its current state is rewritten as a single record.
+// Version 1 does not record the format version of the ssTables.
+type Manifest struct {
+	directory          string
+	file               *os.File
+	tables             map[int]TableEntry
+	nextFileId         int
+	persistedWALOffset int64
+	lastSequence       uint64
+	lock               sync.Mutex
+}
+
+func Exists(directory string) bool {
+	_, err := os.Stat(path.Join(directory, fileName))
+	return err == nil
+}
+
+func NewManifest(directory string) (*Manifest, error) {
+	if len(directory) == 0 {
+		return nil, errors.New("directory can not be empty while creating manifest")
+	}
+	manifest := &Manifest{
+		directory: directory,
+		tables:    make(map[int]TableEntry),
+	}
+	if err := manifest.init(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Apply durably records the edit before applying it to the state of the manifest
+func (manifest *Manifest) Apply(edit *Edit) error {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+
+	if _, err := manifest.file.Write(record(edit)); err != nil {
+		return err
+	}
+	if err := manifest.file.Sync(); err != nil {
+		return err
+	}
+	manifest.apply(edit)
+	return nil
+}
+
+// LiveTables returns the ssTables which are live, ordered by their file id
+func (manifest *Manifest) LiveTables() []TableEntry {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+
+	tables := make([]TableEntry, 0, len(manifest.tables))
+	for _, table := range manifest.tables {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].FileId < tables[j].FileId
+	})
+	return tables
+}
+
+func (manifest *Manifest) NextFileId() int {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+
+	return manifest.nextFileId
+}
+
+func (manifest *Manifest) PersistedWALOffset() int64 {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+
+	return manifest.persistedWALOffset
+}
+
+func (manifest *Manifest) LastSequence() uint64 {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+
+	return manifest.lastSequence
+}
+
+func (manifest *Manifest) Close() error {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+
+	return manifest.file.Close()
+}
+
+func (manifest *Manifest) apply(edit *Edit) {
+	for _, table := range edit.addedTables {
+		manifest.tables[table.FileId] = table
+	}
+	for _, fileId := range edit.removedTables {
+		delete(manifest.tables, fileId)
+	}
+	if edit.nextFileId > manifest.nextFileId {
+		manifest.nextFileId = edit.nextFileId
+	}
+	if edit.persistedWALOffset > manifest.persistedWALOffset {
+		manifest.persistedWALOffset = edit.persistedWALOffset
+	}
+	if edit.lastSequence > manifest.lastSequence {
+		manifest.lastSequence = edit.lastSequence
+	}
+}
+
+func (manifest *Manifest) init() error {
+	replay := func() error {
+		contents, err := ioutil.ReadFile(path.Join(manifest.directory, fileName))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(contents) < headerSize || bigEndian.Uint32(contents) != magic {
+			return errors.New(fmt.Sprintf("%v is not a manifest file", path.Join(manifest.directory, fileName)))
+		}
+		if version := bigEndian.Uint16(contents[4:]); version > currentVersion {
+			return errors.New(fmt.Sprintf("manifest version %v is not supported, supported version is %v", version, currentVersion))
+		}
+		//a partially written or corrupt record can only be the last one, it and anything after it is ignored
+		for offset := headerSize; offset+recordHeaderSize <= len(contents); {
+			size := int(bigEndian.Uint32(contents[offset:]))
+			checksum := bigEndian.Uint32(contents[offset+4:])
+			begin, end := offset+recordHeaderSize, offset+recordHeaderSize+size
+			if end > len(contents) || crc32.Checksum(contents[begin:end], crc32Table) != checksum {
+				break
+			}
+			edit, err := unmarshalEdit(contents[begin:end])
+			if err != nil {
+				return err
+			}
+			manifest.apply(edit)
+			offset = end
+		}
+		return nil
+	}
+	snapshot := func() *Edit {
+		edit := NewEdit().
+			WithNextFileId(manifest.nextFileId).
+			WithPersistedWALOffset(manifest.persistedWALOffset).
+			WithLastSequence(manifest.lastSequence)
+		for _, table := range manifest.tables {
+			edit.AddTable(table.FileId, table.Level, table.Version)
+		}
+		return edit
+	}
+	rewrite := func() error {
+		temporaryFilePath := path.Join(manifest.directory, temporaryFileName)
+		header := make([]byte, headerSize)
+		bigEndian.PutUint32(header, magic)
+		bigEndian.PutUint16(header[4:], currentVersion)
+
+		if err := writeAndSync(temporaryFilePath, append(header, record(snapshot())...)); err != nil {
+			return err
+		}
+		if err := os.Rename(temporaryFilePath, path.Join(manifest.directory, fileName)); err != nil {
+			return err
+		}
+		return syncDirectory(manifest.directory)
+	}
+	open := func() error {
+		file, err := os.OpenFile(path.Join(manifest.directory, fileName), os.O_RDWR|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		manifest.file = file
+		return nil
+	}
+	if err := replay(); err != nil {
+		return err
+	}
+	if err := rewrite(); err != nil {
+		return err
+	}
+	return open()
+}
+
+func record(edit *Edit) []byte {
+	contents := edit.marshal()
+	bytes := make([]byte, recordHeaderSize, recordHeaderSize+len(contents))
+	bigEndian.PutUint32(bytes, uint32(len(contents)))
+	bigEndian.PutUint32(bytes[4:], crc32.Checksum(contents, crc32Table))
+	return append(bytes, contents...)
+}
+
+func writeAndSync(filePath string, contents []byte) error {
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(contents); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,133 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"testing"
+)
+
+func tempDirectory() string {
+	dir, err := ioutil.TempDir(".", "manifest")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return dir
+}
+
+func TestAppliesEditsToTheManifest(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	manifest, _ := NewManifest(directory)
+	defer manifest.Close()
+
+	_ = manifest.Apply(NewEdit().AddTable(1, 0, 6).AddTable(2, 0, 6).WithNextFileId(3))
+	_ = manifest.Apply(NewEdit().RemoveTable(1).RemoveTable(2).AddTable(3, 1, 6).WithNextFileId(4).WithPersistedWALOffset(120))
+
+	liveTables := manifest.LiveTables()
+	if len(liveTables) != 1 || liveTables[0] != (TableEntry{FileId: 3, Level: 1, Version: 6}) {
+		t.Fatalf("Expected table 3 at level 1 to be the only live table, received %v", liveTables)
+	}
+	if manifest.NextFileId() != 4 {
+		t.Fatalf("Expected next file id to be %v, received %v", 4, manifest.NextFileId())
+	}
+	if manifest.PersistedWALOffset() != 120 {
+		t.Fatalf("Expected persisted WAL offset to be %v, received %v", 120, manifest.PersistedWALOffset())
+	}
+}
+
+func TestReloadsTheManifestSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	manifest, _ := NewManifest(directory)
+	_ = manifest.Apply(NewEdit().AddTable(1, 0, 6).AddTable(2, 0, 5).WithNextFileId(3).WithPersistedWALOffset(64).WithLastSequence(12))
+	_ = manifest.Apply(NewEdit().RemoveTable(1))
+	manifest.Close()
+
+	reloaded, err := NewManifest(directory)
+	if err != nil {
+		t.Fatalf("Expected no error while reloading the manifest, received %v", err)
+	}
+	defer reloaded.Close()
+
+	liveTables := reloaded.LiveTables()
+	if len(liveTables) != 1 || liveTables[0] != (TableEntry{FileId: 2, Level: 0, Version: 5}) {
+		t.Fatalf("Expected table 2 of version 5 to be the only live table, received %v", liveTables)
+	}
+	if reloaded.NextFileId() != 3 {
+		t.Fatalf("Expected next file id to be %v, received %v", 3, reloaded.NextFileId())
+	}
+	if reloaded.PersistedWALOffset() != 64 {
+		t.Fatalf("Expected persisted WAL offset to be %v, received %v", 64, reloaded.PersistedWALOffset())
+	}
+	if reloaded.LastSequence() != 12 {
+		t.Fatalf("Expected last sequence to be %v, received %v", 12, reloaded.LastSequence())
+	}
+}
+
+func TestIgnoresAPartiallyWrittenLastRecordSimulatingACrash(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	manifest, _ := NewManifest(directory)
+	_ = manifest.Apply(NewEdit().AddTable(1, 0, 6).WithNextFileId(2))
+	_ = manifest.Apply(NewEdit().AddTable(2, 0, 6).WithNextFileId(3))
+	manifest.Close()
+
+	filePath := path.Join(directory, fileName)
+	stat, _ := os.Stat(filePath)
+	_ = os.Truncate(filePath, stat.Size()-2)
+
+	reloaded, err := NewManifest(directory)
+	if err != nil {
+		t.Fatalf("Expected no error while reloading the manifest, received %v", err)
+	}
+	defer reloaded.Close()
+
+	liveTables := reloaded.LiveTables()
+	if len(liveTables) != 1 || liveTables[0].FileId != 1 {
+		t.Fatalf("Expected table 1 to be the only live table, received %v", liveTables)
+	}
+	_ = reloaded.Apply(NewEdit().AddTable(3, 0, 6))
+	if len(reloaded.LiveTables()) != 2 {
+		t.Fatalf("Expected 2 live tables after applying an edit past the truncated record, received %v", reloaded.LiveTables())
+	}
+}
+
+func TestFailsToOpenAManifestWithAnUnsupportedVersion(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	header := make([]byte, headerSize)
+	bigEndian.PutUint32(header, magic)
+	bigEndian.PutUint16(header[4:], currentVersion+1)
+	_ = ioutil.WriteFile(path.Join(directory, fileName), header, 0644)
+
+	if _, err := NewManifest(directory); err == nil {
+		t.Fatalf("Expected an error while opening a manifest with an unsupported version")
+	}
+}
+
+func TestReloadsAVersion1ManifestWithoutTheVersionsOfTheTables(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	header := make([]byte, headerSize)
+	bigEndian.PutUint32(header, magic)
+	bigEndian.PutUint16(header[4:], 1)
+	_ = ioutil.WriteFile(path.Join(directory, fileName), append(header, record(NewEdit().AddTable(1, 0, 0).WithNextFileId(2))...), 0644)
+
+	manifest, err := NewManifest(directory)
+	if err != nil {
+		t.Fatalf("Expected no error while opening a version 1 manifest, received %v", err)
+	}
+	defer manifest.Close()
+
+	liveTables := manifest.LiveTables()
+	if len(liveTables) != 1 || liveTables[0] != (TableEntry{FileId: 1, Level: 0, Version: 0}) {
+		t.Fatalf("Expected table 1 without a version to be the only live table, received %v", liveTables)
+	}
+}
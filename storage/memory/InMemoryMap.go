@@ -7,24 +7,34 @@ import (
 )
 
 type InMemoryMap struct {
-	keyValues map[string]model.Slice
+	keyValues map[string]model.KeyValuePair
 }
 
 func NewInMemoryMap() *InMemoryMap {
 	return &InMemoryMap{
-		keyValues: make(map[string]model.Slice),
+		keyValues: make(map[string]model.KeyValuePair),
 	}
 }
 
-func (inMemoryMap *InMemoryMap) Put(key model.Slice, value model.Slice) bool {
-	keyAsString := key.AsString()
-	//Assigment:Memtable:1:fill in the 'if' condition to check if the key is present, return false
-	inMemoryMap.keyValues[keyAsString] = value
-	return true
+func (inMemoryMap *InMemoryMap) Put(key model.Slice, value model.Slice) (model.KeyValuePair, bool) {
+	return inMemoryMap.replace(model.KeyValuePair{Key: key, Value: value})
+}
+
+func (inMemoryMap *InMemoryMap) Delete(key model.Slice) (model.KeyValuePair, bool) {
+	return inMemoryMap.replace(model.KeyValuePair{Key: key, Value: model.NilSlice(), Deleted: true})
 }
 
 func (inMemoryMap *InMemoryMap) Get(key model.Slice) model.GetResult {
-	//Assigment:Memtable:2:Perform get
+	if keyValuePair, ok := inMemoryMap.keyValues[key.AsString()]; ok {
+		if keyValuePair.Deleted {
+			return model.GetResult{Key: key, Value: model.NilSlice(), Exists: false, Deleted: true}
+		}
+		return model.GetResult{
+			Key:    key,
+			Value:  keyValuePair.Value,
+			Exists: true,
+		}
+	}
 	return model.GetResult{
 		Key:    key,
 		Value:  model.NilSlice(),
@@ -38,7 +48,7 @@ func (inMemoryMap *InMemoryMap) MultiGet(keys []model.Slice) (model.MultiGetResu
 
 	for _, key := range keys {
 		getResult := inMemoryMap.Get(key)
-		if getResult.Exists {
+		if getResult.Exists || getResult.Deleted {
 			response.Add(getResult)
 		} else {
 			missingKeys = append(missingKeys, key)
@@ -47,10 +57,17 @@ func (inMemoryMap *InMemoryMap) MultiGet(keys []model.Slice) (model.MultiGetResu
 	return response, missingKeys
 }
 
+func (inMemoryMap *InMemoryMap) replace(keyValuePair model.KeyValuePair) (model.KeyValuePair, bool) {
+	keyAsString := keyValuePair.Key.AsString()
+	existing, ok := inMemoryMap.keyValues[keyAsString]
+	inMemoryMap.keyValues[keyAsString] = keyValuePair
+	return existing, ok
+}
+
 func (inMemoryMap *InMemoryMap) AllKeyValues(keyComparator comparator.KeyComparator) []model.KeyValuePair {
 	var pairs []model.KeyValuePair
-	for key, value := range inMemoryMap.keyValues {
-		pairs = append(pairs, model.KeyValuePair{Key: model.NewSlice([]byte(key)), Value: value})
+	for _, keyValuePair := range inMemoryMap.keyValues {
+		pairs = append(pairs, keyValuePair)
 	}
 
 	sort.SliceStable(pairs, func(i, j int) bool {
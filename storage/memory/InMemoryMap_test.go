@@ -118,3 +118,21 @@ func TestGetsAllKeyValuesInMemoryMap(t *testing.T) {
 		t.Fatalf("Expected persistent value to be %v received %v", value.AsString(), keyValuePairs[0].Value.AsString())
 	}
 }
+
+func TestUpdatesTheValueOfAnExistingKeyInMemoryMap(t *testing.T) {
+	sentinelNode := NewInMemoryMap()
+
+	key := model.NewSlice([]byte("HDD"))
+	sentinelNode.Put(key, model.NewSlice([]byte("Hard disk")))
+	existing, replaced := sentinelNode.Put(key, model.NewSlice([]byte("Hard disk drive")))
+
+	if !replaced {
+		t.Fatalf("Expected value of key %v to be replaced, but was not", "HDD")
+	}
+	if existing.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected replaced value to be %v, received %v", "Hard disk", existing.Value.AsString())
+	}
+	if getResult := sentinelNode.Get(key); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+}
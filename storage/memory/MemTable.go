@@ -6,44 +6,80 @@ import (
 	"storage-engine-workshop/storage/utils"
 )
 
+// MemTable is safe for one writer and many concurrent readers.
+// Put and Delete must be called from a single goroutine, Get, MultiGet and iterators can be used from any goroutine.
+// Put, PutValuePointer and Delete write at sequence 0, PutKeyValuePair keeps every version of a key written at a different sequence.
 type MemTable struct {
-	//head           *Node
-	inMemoryMap    *InMemoryMap
+	head           *Node
 	size           uint64
 	totalKeys      int
+	lastSequence   uint64
 	keyComparator  comparator.KeyComparator
 	levelGenerator utils.LevelGenerator
 }
 
 func NewMemTable(maxLevel int, keyComparator comparator.KeyComparator) *MemTable {
 	return &MemTable{
-		//head:           NewNode(model.NilSlice(), model.NilSlice(), maxLevel),
-		inMemoryMap:    NewInMemoryMap(),
+		head:           NewNode(model.NilSlice(), model.NilSlice(), maxLevel),
 		size:           0,
 		keyComparator:  keyComparator,
 		levelGenerator: utils.NewLevelGenerator(maxLevel),
 	}
 }
 
-func (memTable *MemTable) Put(key, value model.Slice) bool {
-	if ok := memTable.inMemoryMap.Put(key, value); ok {
-		memTable.size = memTable.size + uint64(key.Size()) + uint64(value.Size())
-		memTable.totalKeys = memTable.totalKeys + 1
-		return ok
+func (memTable *MemTable) Put(key, value model.Slice) {
+	existing, replaced := memTable.head.Put(key, value, memTable.keyComparator, memTable.levelGenerator)
+	memTable.adjustSize(model.KeyValuePair{Key: key, Value: value}, existing, replaced)
+}
+
+// PutValuePointer puts the encoded pointer to the value in the value log, instead of the value
+func (memTable *MemTable) PutValuePointer(key, valuePointer model.Slice) {
+	existing, replaced := memTable.head.PutValuePointer(key, valuePointer, memTable.keyComparator, memTable.levelGenerator)
+	memTable.adjustSize(model.KeyValuePair{Key: key, Value: valuePointer}, existing, replaced)
+}
+
+func (memTable *MemTable) Delete(key model.Slice) {
+	existing, replaced := memTable.head.Delete(key, memTable.keyComparator, memTable.levelGenerator)
+	memTable.adjustSize(model.KeyValuePair{Key: key, Value: model.NilSlice()}, existing, replaced)
+}
+
+// PutKeyValuePair puts the version of the key at the sequence of the keyValuePair, which is a tombstone if the keyValuePair is deleted
+func (memTable *MemTable) PutKeyValuePair(keyValuePair model.KeyValuePair) {
+	existing, replaced := memTable.head.PutKeyValuePair(keyValuePair, memTable.keyComparator, memTable.levelGenerator)
+	memTable.adjustSize(keyValuePair, existing, replaced)
+	if keyValuePair.Sequence > memTable.lastSequence {
+		memTable.lastSequence = keyValuePair.Sequence
 	}
-	return false
 }
 
 func (memTable *MemTable) Get(key model.Slice) model.GetResult {
-	return memTable.inMemoryMap.Get(key)
+	return memTable.head.Get(key, memTable.keyComparator)
+}
+
+// GetAt returns the newest version of the key with a sequence less than or equal to the sequence
+func (memTable *MemTable) GetAt(key model.Slice, sequence uint64) model.GetResult {
+	return memTable.head.GetAt(key, sequence, memTable.keyComparator)
 }
 
 func (memTable *MemTable) MultiGet(keys []model.Slice) (model.MultiGetResult, []model.Slice) {
-	return memTable.inMemoryMap.MultiGet(keys)
+	return memTable.head.MultiGet(keys, memTable.keyComparator)
+}
+
+func (memTable *MemTable) MultiGetAt(keys []model.Slice, sequence uint64) (model.MultiGetResult, []model.Slice) {
+	return memTable.head.MultiGetAt(keys, sequence, memTable.keyComparator)
 }
 
 func (memTable *MemTable) AllKeyValues() []model.KeyValuePair {
-	return memTable.inMemoryMap.AllKeyValues(memTable.keyComparator)
+	return memTable.head.AllKeyValues()
+}
+
+func (memTable *MemTable) NewIterator() *MemTableIterator {
+	return memTable.NewIteratorAt(model.LatestSequence)
+}
+
+// NewIteratorAt returns an iterator over the newest version of each key with a sequence less than or equal to the sequence
+func (memTable *MemTable) NewIteratorAt(sequence uint64) *MemTableIterator {
+	return newMemTableIterator(memTable.head, sequence, memTable.keyComparator)
 }
 
 func (memTable *MemTable) TotalSize() uint64 {
@@ -53,3 +89,20 @@ func (memTable *MemTable) TotalSize() uint64 {
 func (memTable *MemTable) TotalKeys() int {
 	return memTable.totalKeys
 }
+
+// LastSequence returns the highest sequence put in the memTable
+func (memTable *MemTable) LastSequence() uint64 {
+	return memTable.lastSequence
+}
+
+// adjustSize accounts for every version of a key, the existing version is replaced only if it has the same sequence
+func (memTable *MemTable) adjustSize(keyValuePair model.KeyValuePair, existing model.KeyValuePair, existed bool) {
+	memTable.size = memTable.size + uint64(keyValuePair.Key.Size()) + uint64(keyValuePair.Value.Size())
+	if existed {
+		if existing.Sequence == keyValuePair.Sequence {
+			memTable.size = memTable.size - uint64(existing.Key.Size()) - uint64(existing.Value.Size())
+		}
+		return
+	}
+	memTable.totalKeys = memTable.totalKeys + 1
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+)
+
+// MemTableIterator returns the newest version of each key with a sequence less than or equal to the sequence of the iterator,
+// keys without such a version are skipped
+type MemTableIterator struct {
+	head          *Node
+	current       *Node
+	entry         *nodeEntry
+	sequence      uint64
+	keyComparator comparator.KeyComparator
+}
+
+func newMemTableIterator(head *Node, sequence uint64, keyComparator comparator.KeyComparator) *MemTableIterator {
+	return &MemTableIterator{
+		head:          head,
+		sequence:      sequence,
+		keyComparator: keyComparator,
+	}
+}
+
+func (memTableIterator *MemTableIterator) Seek(key model.Slice) {
+	memTableIterator.moveTo(memTableIterator.head.nodeGreaterThanOrEqualTo(key, memTableIterator.keyComparator))
+}
+
+func (memTableIterator *MemTableIterator) Next() {
+	if memTableIterator.IsValid() {
+		memTableIterator.moveTo(memTableIterator.current.next(0))
+	}
+}
+
+func (memTableIterator *MemTableIterator) IsValid() bool {
+	return memTableIterator.current != nil
+}
+
+func (memTableIterator *MemTableIterator) Key() model.Slice {
+	return memTableIterator.current.key
+}
+
+func (memTableIterator *MemTableIterator) Value() model.Slice {
+	return memTableIterator.entry.value
+}
+
+func (memTableIterator *MemTableIterator) IsDeleted() bool {
+	return memTableIterator.entry.deleted
+}
+
+func (memTableIterator *MemTableIterator) IsValuePointer() bool {
+	return memTableIterator.entry.valuePointer
+}
+
+func (memTableIterator *MemTableIterator) Sequence() uint64 {
+	return memTableIterator.entry.sequence
+}
+
+// Err returns nil, a memTable is in memory and can not fail to be read
+func (memTableIterator *MemTableIterator) Err() error {
+	return nil
+}
+
+func (memTableIterator *MemTableIterator) Close() {
+	memTableIterator.current = nil
+	memTableIterator.entry = nil
+}
+
+func (memTableIterator *MemTableIterator) moveTo(node *Node) {
+	for ; node != nil; node = node.next(0) {
+		if entry := node.loadEntry().visibleAt(memTableIterator.sequence); entry != nil {
+			memTableIterator.current, memTableIterator.entry = node, entry
+			return
+		}
+	}
+	memTableIterator.current = nil
+}
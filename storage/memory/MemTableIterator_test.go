@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"testing"
+)
+
+func TestIteratesOverAllKeysInMemTableInOrder(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	memTable.Put(model.NewSlice([]byte("PMEM")), model.NewSlice([]byte("Persistent memory")))
+
+	iterator := memTable.NewIterator()
+	iterator.Seek(model.NilSlice())
+
+	var keys []string
+	for ; iterator.IsValid(); iterator.Next() {
+		keys = append(keys, iterator.Key().AsString())
+	}
+	expected := []string{"HDD", "PMEM", "SDD"}
+	for index, key := range expected {
+		if keys[index] != key {
+			t.Fatalf("Expected key to be %v, received %v", key, keys[index])
+		}
+	}
+}
+
+func TestSeeksToTheFirstKeyGreaterThanOrEqualToTheGivenKeyInMemTable(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	memTable.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+
+	iterator := memTable.NewIterator()
+	iterator.Seek(model.NewSlice([]byte("PMEM")))
+
+	if !iterator.IsValid() {
+		t.Fatalf("Expected iterator to be valid after seek, but was not")
+	}
+	if iterator.Value().AsString() != "Solid state" {
+		t.Fatalf("Expected %v, received %v", "Solid state", iterator.Value().AsString())
+	}
+	iterator.Next()
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
+
+func TestIteratesOverTheVersionsVisibleAtASequenceInMemTable(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk")), Sequence: 1})
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("PMEM")), Value: model.NewSlice([]byte("Persistent memory")), Sequence: 3})
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk drive")), Sequence: 4})
+
+	iterator := memTable.NewIteratorAt(2)
+	iterator.Seek(model.NilSlice())
+
+	if !iterator.IsValid() || iterator.Value().AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", iterator.Value().AsString())
+	}
+	iterator.Next()
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
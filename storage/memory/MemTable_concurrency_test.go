@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestPutsKeyValuesWithOneWriterAndGetsWithConcurrentReaders(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+
+	keyUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Key-" + strconv.Itoa(count)))
+	}
+	valueUsing := func(count int) model.Slice {
+		return model.NewSlice([]byte("Value-" + strconv.Itoa(count)))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+
+	go func() {
+		defer wg.Done()
+		for count := 1; count <= 1000; count++ {
+			memTable.Put(keyUsing(count), valueUsing(count))
+		}
+	}()
+	for reader := 1; reader <= 4; reader++ {
+		go func() {
+			defer wg.Done()
+			for count := 1; count <= 1000; count++ {
+				getResult := memTable.Get(keyUsing(count))
+				if getResult.Exists && getResult.Value.AsString() != valueUsing(count).AsString() {
+					t.Errorf("Expected %v, received %v", valueUsing(count).AsString(), getResult.Value.AsString())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for count := 1; count <= 1000; count++ {
+		if getResult := memTable.Get(keyUsing(count)); getResult.Value.AsString() != valueUsing(count).AsString() {
+			t.Fatalf("Expected %v, received %v", valueUsing(count).AsString(), getResult.Value.AsString())
+		}
+	}
+}
+
+func TestIteratesWithAConcurrentWriter(t *testing.T) {
+	keyComparator := comparator.StringKeyComparator{}
+	memTable := NewMemTable(10, keyComparator)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for count := 1; count <= 1000; count++ {
+			memTable.Put(model.NewSlice([]byte("Key-"+strconv.Itoa(count))), model.NewSlice([]byte("Value")))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for attempt := 1; attempt <= 10; attempt++ {
+			iterator := memTable.NewIterator()
+			var previous model.Slice
+			for iterator.Seek(model.NilSlice()); iterator.IsValid(); iterator.Next() {
+				if previous.Size() > 0 && keyComparator.Compare(previous, iterator.Key()) >= 0 {
+					t.Errorf("Expected keys in increasing order, received %v after %v", iterator.Key().AsString(), previous.AsString())
+				}
+				previous = iterator.Key()
+			}
+		}
+	}()
+	wg.Wait()
+}
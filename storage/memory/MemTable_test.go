@@ -104,3 +104,129 @@ func TestReturnsTheTotalMemTableSize(t *testing.T) {
 		t.Fatalf("Expected total memtable size to be %v, received %v", expected, size)
 	}
 }
+
+func TestDeletesAKeyInMemTable(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	key := model.NewSlice([]byte("HDD"))
+	memTable.Put(key, model.NewSlice([]byte("Hard disk")))
+	memTable.Delete(key)
+
+	getResult := memTable.Get(key)
+	if getResult.Exists {
+		t.Fatalf("Expected key %v to be deleted, but was present", "HDD")
+	}
+	if !getResult.Deleted {
+		t.Fatalf("Expected a tombstone for key %v, but received none", "HDD")
+	}
+}
+
+func TestPutsAKeyAfterDeletingItInMemTable(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	key := model.NewSlice([]byte("HDD"))
+	memTable.Put(key, model.NewSlice([]byte("Hard disk")))
+	memTable.Delete(key)
+	memTable.Put(key, model.NewSlice([]byte("Hard disk drive")))
+
+	getResult := memTable.Get(key)
+	if getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+}
+
+func TestDeletesAKeyAndGetsTombstoneInAllKeyValues(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Delete(model.NewSlice([]byte("HDD")))
+
+	keyValuePairs := memTable.AllKeyValues()
+
+	if !keyValuePairs[0].Deleted {
+		t.Fatalf("Expected key %v to be a tombstone in all keys but was not", "HDD")
+	}
+}
+
+func TestUpdatesTheValueOfAnExistingKeyInMemTable(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	key := model.NewSlice([]byte("HDD"))
+	memTable.Put(key, model.NewSlice([]byte("Hard disk")))
+	memTable.Put(key, model.NewSlice([]byte("Hard disk drive")))
+
+	getResult := memTable.Get(key)
+	if getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+	if totalKeys := memTable.TotalKeys(); totalKeys != 1 {
+		t.Fatalf("Expected %v keys but received %v", 1, totalKeys)
+	}
+}
+
+func TestAdjustsTheTotalMemTableSizeOnUpdatingAnExistingKey(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	key := model.NewSlice([]byte("HDD"))
+	memTable.Put(key, model.NewSlice([]byte("Hard disk drive")))
+
+	value := model.NewSlice([]byte("Disk"))
+	memTable.Put(key, value)
+
+	size := memTable.TotalSize()
+	expected := key.Size() + value.Size()
+
+	if size != uint64(expected) {
+		t.Fatalf("Expected total memtable size to be %v, received %v", expected, size)
+	}
+}
+
+func TestAdjustsTheTotalMemTableSizeOnDeletingAnExistingKey(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	key := model.NewSlice([]byte("HDD"))
+	memTable.Put(key, model.NewSlice([]byte("Hard disk")))
+	memTable.Delete(key)
+
+	size := memTable.TotalSize()
+	expected := key.Size()
+
+	if size != uint64(expected) {
+		t.Fatalf("Expected total memtable size to be %v, received %v", expected, size)
+	}
+}
+
+func TestGetsTheVersionOfAKeyVisibleAtASequenceInMemTable(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	key := model.NewSlice([]byte("HDD"))
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: key, Value: model.NewSlice([]byte("Hard disk")), Sequence: 2})
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: key, Value: model.NewSlice([]byte("Hard disk drive")), Sequence: 5})
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: key, Deleted: true, Sequence: 7})
+
+	if getResult := memTable.GetAt(key, 1); getResult.Exists || getResult.Deleted {
+		t.Fatalf("Expected no version of key %v at sequence %v, received %v", "HDD", 1, getResult)
+	}
+	if getResult := memTable.GetAt(key, 4); getResult.Value.AsString() != "Hard disk" || getResult.Sequence != 2 {
+		t.Fatalf("Expected %v at sequence %v, received %v at sequence %v", "Hard disk", 2, getResult.Value.AsString(), getResult.Sequence)
+	}
+	if getResult := memTable.GetAt(key, 6); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+	if getResult := memTable.Get(key); !getResult.Deleted {
+		t.Fatalf("Expected the latest version of key %v to be deleted", "HDD")
+	}
+	if versions := len(memTable.AllKeyValues()); versions != 3 {
+		t.Fatalf("Expected %v versions, received %v", 3, versions)
+	}
+	if memTable.LastSequence() != 7 {
+		t.Fatalf("Expected last sequence %v, received %v", 7, memTable.LastSequence())
+	}
+}
+
+func TestReplacesTheVersionOfAKeyPutAtTheSameSequenceInMemTable(t *testing.T) {
+	memTable := NewMemTable(10, comparator.StringKeyComparator{})
+	key := model.NewSlice([]byte("HDD"))
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: key, Value: model.NewSlice([]byte("Hard disk")), Sequence: 3})
+	memTable.PutKeyValuePair(model.KeyValuePair{Key: key, Value: model.NewSlice([]byte("Hard disk drive")), Sequence: 3})
+
+	allKeyValues := memTable.AllKeyValues()
+	if len(allKeyValues) != 1 || allKeyValues[0].Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected only the version %v, received %v", "Hard disk drive", allKeyValues)
+	}
+	if memTable.TotalSize() != uint64(len("HDD")+len("Hard disk drive")) {
+		t.Fatalf("Expected size %v, received %v", len("HDD")+len("Hard disk drive"), memTable.TotalSize())
+	}
+}
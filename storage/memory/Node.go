@@ -5,99 +5,204 @@ import (
 	"storage-engine-workshop/db/model"
 	"storage-engine-workshop/storage/comparator"
 	"storage-engine-workshop/storage/utils"
+	"sync/atomic"
+	"unsafe"
 )
 
+// Node is a skiplist node which supports one writer and many concurrent readers without locks.
+// A new node is fully built before it is published, and links and entries are only ever read and written atomically.
+// A node keeps every version of its key as a chain of entries, newest first. A write at the sequence of the newest entry replaces it.
 type Node struct {
 	key      model.Slice
-	value    model.Slice
-	forwards []*Node
+	entry    unsafe.Pointer
+	forwards []unsafe.Pointer
+}
+
+type nodeEntry struct {
+	value        model.Slice
+	deleted      bool
+	valuePointer bool
+	sequence     uint64
+	older        *nodeEntry
 }
 
 func NewNode(key model.Slice, value model.Slice, level int) *Node {
+	return newNodeWith(key, &nodeEntry{value: value}, level)
+}
+
+func newNodeWith(key model.Slice, entry *nodeEntry, level int) *Node {
 	return &Node{
 		key:      key,
-		value:    value,
-		forwards: make([]*Node, level),
+		entry:    unsafe.Pointer(entry),
+		forwards: make([]unsafe.Pointer, level),
 	}
 }
 
-func (node *Node) Put(key model.Slice, value model.Slice, keyComparator comparator.KeyComparator, levelGenerator utils.LevelGenerator) bool {
+func (node *Node) Put(key model.Slice, value model.Slice, keyComparator comparator.KeyComparator, levelGenerator utils.LevelGenerator) (model.KeyValuePair, bool) {
+	return node.put(key, &nodeEntry{value: value}, keyComparator, levelGenerator)
+}
+
+func (node *Node) PutValuePointer(key model.Slice, valuePointer model.Slice, keyComparator comparator.KeyComparator, levelGenerator utils.LevelGenerator) (model.KeyValuePair, bool) {
+	return node.put(key, &nodeEntry{value: valuePointer, valuePointer: true}, keyComparator, levelGenerator)
+}
+
+func (node *Node) Delete(key model.Slice, keyComparator comparator.KeyComparator, levelGenerator utils.LevelGenerator) (model.KeyValuePair, bool) {
+	return node.put(key, &nodeEntry{value: model.NilSlice(), deleted: true}, keyComparator, levelGenerator)
+}
+
+// PutKeyValuePair puts the version of the key at the sequence of the keyValuePair, which is a tombstone if the keyValuePair is deleted
+func (node *Node) PutKeyValuePair(keyValuePair model.KeyValuePair, keyComparator comparator.KeyComparator, levelGenerator utils.LevelGenerator) (model.KeyValuePair, bool) {
+	entry := &nodeEntry{
+		value:        keyValuePair.Value,
+		deleted:      keyValuePair.Deleted,
+		valuePointer: keyValuePair.ValuePointer,
+		sequence:     keyValuePair.Sequence,
+	}
+	if entry.deleted {
+		entry.value = model.NilSlice()
+	}
+	return node.put(keyValuePair.Key, entry, keyComparator, levelGenerator)
+}
+
+func (node *Node) put(key model.Slice, entry *nodeEntry, keyComparator comparator.KeyComparator, levelGenerator utils.LevelGenerator) (model.KeyValuePair, bool) {
 	current := node
 	positions := make([]*Node, len(node.forwards))
 
 	for level := len(node.forwards) - 1; level >= 0; level-- {
-		for current.forwards[level] != nil && keyComparator.Compare(current.forwards[level].key, key) < 0 {
-			//Assigment:Memtable:1:fill in the 'and' condition
-			current = current.forwards[level]
+		for current.next(level) != nil && keyComparator.Compare(current.next(level).key, key) < 0 {
+			current = current.next(level)
 		}
 		positions[level] = current
 	}
 
-	current = current.forwards[0]
+	current = current.next(0)
 	if current == nil || keyComparator.Compare(current.key, key) != 0 {
 		newLevel := levelGenerator.Generate()
-		//Assignment:Memtable:2:generate new level
-		newNode := NewNode(key, value, newLevel)
+		newNode := newNodeWith(key, entry, newLevel)
+		for level := 0; level < newLevel; level++ {
+			newNode.setNext(level, positions[level].next(level))
+		}
 		for level := 0; level < newLevel; level++ {
-			newNode.forwards[level] = positions[level].forwards[level]
-			positions[level].forwards[level] = newNode
+			positions[level].setNext(level, newNode)
 		}
-		return true
+		return model.KeyValuePair{}, false
+	}
+	existing := current.loadEntry()
+	entry.older = existing
+	if existing.sequence == entry.sequence {
+		entry.older = existing.older
 	}
-	return false
+	atomic.StorePointer(&current.entry, unsafe.Pointer(entry))
+	return existing.keyValuePair(current.key), true
 }
 
 func (node *Node) Get(key model.Slice, keyComparator comparator.KeyComparator) model.GetResult {
+	return node.GetAt(key, model.LatestSequence, keyComparator)
+}
+
+// GetAt returns the newest version of the key with a sequence less than or equal to the sequence
+func (node *Node) GetAt(key model.Slice, sequence uint64, keyComparator comparator.KeyComparator) model.GetResult {
 	node, ok := node.nodeMatching(key, keyComparator)
 	if ok {
-		return model.GetResult{Key: key, Value: node.value, Exists: ok}
+		if entry := node.loadEntry().visibleAt(sequence); entry != nil {
+			return entry.getResult(key)
+		}
 	}
 	return model.GetResult{Key: key, Value: model.NilSlice(), Exists: false}
 }
 
 func (node *Node) MultiGet(keys []model.Slice, keyComparator comparator.KeyComparator) (model.MultiGetResult, []model.Slice) {
-	sort.SliceStable(keys, func(i, j int) bool {
-		return keyComparator.Compare(keys[i], keys[j]) < 0
+	return node.MultiGetAt(keys, model.LatestSequence, keyComparator)
+}
+
+// MultiGetAt returns the newest versions of the keys with a sequence less than or equal to the sequence,
+// along with the keys which have no such version
+func (node *Node) MultiGetAt(keys []model.Slice, sequence uint64, keyComparator comparator.KeyComparator) (model.MultiGetResult, []model.Slice) {
+	sortedKeys := make([]model.Slice, len(keys))
+	copy(sortedKeys, keys)
+	sort.SliceStable(sortedKeys, func(i, j int) bool {
+		return keyComparator.Compare(sortedKeys[i], sortedKeys[j]) < 0
 	})
 	currentNode := node
 	response := model.MultiGetResult{}
 	var missingKeys []model.Slice
 
-	for _, key := range keys {
+	for _, key := range sortedKeys {
 		targetNode, ok := currentNode.nodeMatching(key, keyComparator)
 		if ok {
-			response.Add(model.GetResult{Key: key, Value: targetNode.value, Exists: ok})
 			currentNode = targetNode
-		} else {
-			missingKeys = append(missingKeys, key)
+			if entry := targetNode.loadEntry().visibleAt(sequence); entry != nil {
+				response.Add(entry.getResult(key))
+				continue
+			}
 		}
+		missingKeys = append(missingKeys, key)
 	}
 	return response, missingKeys
 }
 
+// AllKeyValues returns every version of every key, ordered by key and newest version first
 func (node *Node) AllKeyValues() []model.KeyValuePair {
 	level, current := 0, node
 	var pairs []model.KeyValuePair
 
-	current = current.forwards[level]
+	current = current.next(level)
 	for current != nil {
-		pairs = append(pairs, model.KeyValuePair{Key: current.key, Value: current.value})
-		current = current.forwards[level]
+		for entry := current.loadEntry(); entry != nil; entry = entry.older {
+			pairs = append(pairs, entry.keyValuePair(current.key))
+		}
+		current = current.next(level)
 	}
 	return pairs
 }
 
 func (node *Node) nodeMatching(key model.Slice, keyComparator comparator.KeyComparator) (*Node, bool) {
+	current := node.nodeGreaterThanOrEqualTo(key, keyComparator)
+	if current != nil && keyComparator.Compare(current.key, key) == 0 {
+		return current, true
+	}
+	return nil, false
+}
+
+func (node *Node) nodeGreaterThanOrEqualTo(key model.Slice, keyComparator comparator.KeyComparator) *Node {
 	current := node
 	for level := len(node.forwards) - 1; level >= 0; level-- {
-		//Assignment:Memtable:3:fill in the 'and' condition
-		for current.forwards[level] != nil && keyComparator.Compare(current.forwards[level].key, key) < 0 {
-			current = current.forwards[level]
+		for current.next(level) != nil && keyComparator.Compare(current.next(level).key, key) < 0 {
+			current = current.next(level)
 		}
 	}
-	current = current.forwards[0]
-	if current != nil && keyComparator.Compare(current.key, key) == 0 {
-		return current, true
+	return current.next(0)
+}
+
+func (node *Node) next(level int) *Node {
+	return (*Node)(atomic.LoadPointer(&node.forwards[level]))
+}
+
+func (node *Node) setNext(level int, next *Node) {
+	atomic.StorePointer(&node.forwards[level], unsafe.Pointer(next))
+}
+
+func (node *Node) loadEntry() *nodeEntry {
+	return (*nodeEntry)(atomic.LoadPointer(&node.entry))
+}
+
+// visibleAt returns the newest version with a sequence less than or equal to the sequence, nil if there is none
+func (entry *nodeEntry) visibleAt(sequence uint64) *nodeEntry {
+	for ; entry != nil; entry = entry.older {
+		if entry.sequence <= sequence {
+			return entry
+		}
 	}
-	return nil, false
+	return nil
+}
+
+func (entry *nodeEntry) getResult(key model.Slice) model.GetResult {
+	if entry.deleted {
+		return model.GetResult{Key: key, Value: model.NilSlice(), Exists: false, Deleted: true, Sequence: entry.sequence}
+	}
+	return model.GetResult{Key: key, Value: entry.value, Exists: true, ValuePointer: entry.valuePointer, Sequence: entry.sequence}
+}
+
+func (entry *nodeEntry) keyValuePair(key model.Slice) model.KeyValuePair {
+	return model.KeyValuePair{Key: key, Value: entry.value, Deleted: entry.deleted, ValuePointer: entry.valuePointer, Sequence: entry.sequence}
 }
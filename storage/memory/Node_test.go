@@ -125,3 +125,24 @@ func TestGetsAllKeyValues(t *testing.T) {
 		t.Fatalf("Expected persistent value to be %v received %v", value.AsString(), keyValuePairs[0].Value.AsString())
 	}
 }
+
+func TestUpdatesTheValueOfAnExistingKeyInNode(t *testing.T) {
+	const maxLevel = 8
+	keyComparator := comparator.StringKeyComparator{}
+
+	sentinelNode := NewNode(model.NilSlice(), model.NilSlice(), maxLevel)
+
+	key := model.NewSlice([]byte("HDD"))
+	sentinelNode.Put(key, model.NewSlice([]byte("Hard disk")), keyComparator, utils.NewLevelGenerator(maxLevel))
+	existing, replaced := sentinelNode.Put(key, model.NewSlice([]byte("Hard disk drive")), keyComparator, utils.NewLevelGenerator(maxLevel))
+
+	if !replaced {
+		t.Fatalf("Expected value of key %v to be replaced, but was not", "HDD")
+	}
+	if existing.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected replaced value to be %v, received %v", "Hard disk", existing.Value.AsString())
+	}
+	if getResult := sentinelNode.Get(key, keyComparator); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+}
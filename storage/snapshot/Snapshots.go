@@ -0,0 +1,65 @@
+package snapshot
+
+import (
+	"container/list"
+	"storage-engine-workshop/db/model"
+	"sync"
+)
+
+// Snapshot is a point-in-time view of the database, it sees the writes with a sequence less than or equal to its sequence.
+// A snapshot must be released once it is not needed, compaction keeps the versions which the live snapshots see
+type Snapshot struct {
+	sequence  uint64
+	element   *list.Element
+	snapshots *Snapshots
+}
+
+// Snapshots tracks the live snapshots in the order of their sequence, safe for concurrent use
+type Snapshots struct {
+	live *list.List
+	lock sync.Mutex
+}
+
+func NewSnapshots() *Snapshots {
+	return &Snapshots{
+		live: list.New(),
+	}
+}
+
+// Take returns a snapshot at the sequence returned by currentSequence, which must never decrease.
+// The sequence is read while the live snapshots are locked, keeping them in the order of their sequence
+func (snapshots *Snapshots) Take(currentSequence func() uint64) *Snapshot {
+	snapshots.lock.Lock()
+	defer snapshots.lock.Unlock()
+
+	snapshot := &Snapshot{sequence: currentSequence(), snapshots: snapshots}
+	snapshot.element = snapshots.live.PushBack(snapshot)
+	return snapshot
+}
+
+// OldestSequence returns the sequence of the oldest live snapshot, model.LatestSequence if there is none
+func (snapshots *Snapshots) OldestSequence() uint64 {
+	snapshots.lock.Lock()
+	defer snapshots.lock.Unlock()
+
+	if oldest := snapshots.live.Front(); oldest != nil {
+		return oldest.Value.(*Snapshot).sequence
+	}
+	return model.LatestSequence
+}
+
+func (snapshot *Snapshot) Sequence() uint64 {
+	return snapshot.sequence
+}
+
+// Release allows compaction to drop the versions which only the snapshot sees, releasing it again has no effect
+func (snapshot *Snapshot) Release() {
+	snapshots := snapshot.snapshots
+	snapshots.lock.Lock()
+	defer snapshots.lock.Unlock()
+
+	if snapshot.element != nil {
+		snapshots.live.Remove(snapshot.element)
+		snapshot.element = nil
+	}
+}
@@ -0,0 +1,52 @@
+package snapshot
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReturnsTheSequenceOfTheOldestLiveSnapshotTakenConcurrently(t *testing.T) {
+	snapshots := NewSnapshots()
+	var sequence uint64
+	currentSequence := func() uint64 {
+		return atomic.LoadUint64(&sequence)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for count := 0; count < 1000; count++ {
+			atomic.AddUint64(&sequence, 1)
+		}
+	}()
+	taken := make([]*Snapshot, 1000)
+	go func() {
+		defer wg.Done()
+		var takers sync.WaitGroup
+		takers.Add(len(taken))
+		for index := range taken {
+			go func(index int) {
+				defer takers.Done()
+				taken[index] = snapshots.Take(currentSequence)
+			}(index)
+		}
+		takers.Wait()
+	}()
+	wg.Wait()
+
+	for len(taken) > 0 {
+		oldest := 0
+		for index := range taken {
+			if taken[index].Sequence() < taken[oldest].Sequence() {
+				oldest = index
+			}
+		}
+		if sequence := snapshots.OldestSequence(); sequence != taken[oldest].Sequence() {
+			t.Fatalf("Expected %v, received %v", taken[oldest].Sequence(), sequence)
+		}
+		taken[oldest].Release()
+		taken = append(taken[:oldest], taken[oldest+1:]...)
+	}
+}
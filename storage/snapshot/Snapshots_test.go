@@ -0,0 +1,37 @@
+package snapshot
+
+import (
+	"storage-engine-workshop/db/model"
+	"testing"
+)
+
+func sequenceOf(sequence uint64) func() uint64 {
+	return func() uint64 {
+		return sequence
+	}
+}
+
+func TestReturnsTheSequenceOfTheOldestLiveSnapshot(t *testing.T) {
+	snapshots := NewSnapshots()
+	older := snapshots.Take(sequenceOf(5))
+	_ = snapshots.Take(sequenceOf(9))
+
+	if sequence := snapshots.OldestSequence(); sequence != 5 {
+		t.Fatalf("Expected %v, received %v", 5, sequence)
+	}
+	older.Release()
+	if sequence := snapshots.OldestSequence(); sequence != 9 {
+		t.Fatalf("Expected %v, received %v", 9, sequence)
+	}
+}
+
+func TestReturnsTheLatestSequenceWithoutLiveSnapshots(t *testing.T) {
+	snapshots := NewSnapshots()
+	snapshot := snapshots.Take(sequenceOf(5))
+	snapshot.Release()
+	snapshot.Release()
+
+	if sequence := snapshots.OldestSequence(); sequence != model.LatestSequence {
+		t.Fatalf("Expected %v, received %v", model.LatestSequence, sequence)
+	}
+}
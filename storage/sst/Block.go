@@ -0,0 +1,313 @@
+package sst
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+)
+
+const (
+	targetBlockSizeBytes = 4 * 1024
+	restartInterval      = 16
+	reservedRestartSize  = 4
+	reservedCodecIdSize  = 1
+	reservedChecksumSize = 4
+)
+
+var crc32Table = crc32.MakeTable(crc32.Castagnoli)
+
+type blockEntry struct {
+	key      model.Slice
+	value    model.Slice
+	kind     byte
+	sequence uint64
+}
+
+// blockBuilder encodes sorted entries with their keys delta encoded against the previous key.
+// Every restartInterval entries, a key is stored in full as a restart point, which allows binary search over the restart points.
+// The versions of a key are added newest first.
+// Layout of an entry: uvarint shared key size | uvarint non-shared key size | uvarint value size | uvarint sequence | 1 byte kind | non-shared key | value
+// Layout of a block: entries | 4 bytes offset of each restart point | 4 bytes number of restart points
+type blockBuilder struct {
+	bytes    []byte
+	restarts []uint32
+	entries  int
+	lastKey  model.Slice
+}
+
+func newBlockBuilder() *blockBuilder {
+	return &blockBuilder{}
+}
+
+func (builder *blockBuilder) add(keyValuePair model.KeyValuePair) {
+	builder.addEntry(keyValuePair.Key, keyValuePair.Value, kindOf(keyValuePair), keyValuePair.Sequence)
+}
+
+func (builder *blockBuilder) addEntry(key, value model.Slice, kind byte, sequence uint64) {
+	shared := 0
+	if builder.entries%restartInterval == 0 {
+		builder.restarts = append(builder.restarts, uint32(len(builder.bytes)))
+	} else {
+		shared = sharedPrefixSize(builder.lastKey.GetRawContent(), key.GetRawContent())
+	}
+	buffer := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(value uint64) {
+		size := binary.PutUvarint(buffer, value)
+		builder.bytes = append(builder.bytes, buffer[:size]...)
+	}
+	putUvarint(uint64(shared))
+	putUvarint(uint64(key.Size() - shared))
+	putUvarint(uint64(value.Size()))
+	putUvarint(sequence)
+	builder.bytes = append(builder.bytes, kind)
+	builder.bytes = append(builder.bytes, key.GetRawContent()[shared:]...)
+	builder.bytes = append(builder.bytes, value.GetRawContent()...)
+
+	builder.lastKey = key
+	builder.entries = builder.entries + 1
+}
+
+// finish returns the encoded block, the builder can be reused after a reset
+func (builder *blockBuilder) finish() []byte {
+	bytes := builder.bytes
+	trailer := make([]byte, reservedRestartSize*(len(builder.restarts)+1))
+	for index, restart := range builder.restarts {
+		bigEndian.PutUint32(trailer[index*reservedRestartSize:], restart)
+	}
+	bigEndian.PutUint32(trailer[len(builder.restarts)*reservedRestartSize:], uint32(len(builder.restarts)))
+	return append(bytes, trailer...)
+}
+
+func (builder *blockBuilder) isFull() bool {
+	return len(builder.bytes)+reservedRestartSize*(len(builder.restarts)+1) >= targetBlockSizeBytes
+}
+
+func (builder *blockBuilder) isEmpty() bool {
+	return builder.entries == 0
+}
+
+func (builder *blockBuilder) reset() {
+	builder.bytes, builder.restarts, builder.entries, builder.lastKey = nil, nil, 0, model.NilSlice()
+}
+
+// compressBlock compresses the encoded block, appends the id of the codec used and the crc32c of the compressed block and the codec id.
+// A block which does not shrink on compression is kept uncompressed
+func compressBlock(bytes []byte, compressionCodec CompressionCodec) ([]byte, error) {
+	compressed, err := compressionCodec.Compress(bytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) >= len(bytes) {
+		compressed = append(bytes, noCompressionId)
+	} else {
+		compressed = append(compressed, compressionCodec.Id())
+	}
+	checksum := make([]byte, reservedChecksumSize)
+	bigEndian.PutUint32(checksum, crc32.Checksum(compressed, crc32Table))
+	return append(compressed, checksum...), nil
+}
+
+// block is a decoded view over the contents of a block.
+// Blocks of legacy ssTables store every entry as a PersistentSSTableSlice and have no restart points,
+// they carry no compression codec id, no checksum and their entries are of sequence 0.
+type block struct {
+	contents         []byte
+	restarts         []uint32
+	prefixCompressed bool
+}
+
+func newBlock(bytes []byte, version uint16) (*block, error) {
+	if version == legacyVersion {
+		return &block{contents: bytes}, nil
+	}
+	if len(bytes) < reservedChecksumSize {
+		return nil, errors.New("malformed ssTable block, missing checksum")
+	}
+	checksumOffset := len(bytes) - reservedChecksumSize
+	if crc32.Checksum(bytes[:checksumOffset], crc32Table) != bigEndian.Uint32(bytes[checksumOffset:]) {
+		return nil, errors.New("ssTable block checksum mismatch")
+	}
+	bytes = bytes[:checksumOffset]
+	if len(bytes) < reservedCodecIdSize {
+		return nil, errors.New("malformed ssTable block, missing compression codec id")
+	}
+	compressionCodec, err := compressionCodecWith(bytes[len(bytes)-reservedCodecIdSize])
+	if err != nil {
+		return nil, err
+	}
+	if bytes, err = compressionCodec.Decompress(bytes[:len(bytes)-reservedCodecIdSize]); err != nil {
+		return nil, err
+	}
+	if len(bytes) < reservedRestartSize {
+		return nil, errors.New(fmt.Sprintf("malformed ssTable block of %v bytes", len(bytes)))
+	}
+	totalRestarts := int(bigEndian.Uint32(bytes[len(bytes)-reservedRestartSize:]))
+	restartsBeginOffset := len(bytes) - reservedRestartSize*(totalRestarts+1)
+	if totalRestarts == 0 || restartsBeginOffset < 0 {
+		return nil, errors.New(fmt.Sprintf("malformed ssTable block, invalid number of restart points %v", totalRestarts))
+	}
+	restarts := make([]uint32, totalRestarts)
+	for index := range restarts {
+		restarts[index] = bigEndian.Uint32(bytes[restartsBeginOffset+index*reservedRestartSize:])
+		if int(restarts[index]) >= restartsBeginOffset {
+			return nil, errors.New(fmt.Sprintf("malformed ssTable block, invalid restart point %v", restarts[index]))
+		}
+	}
+	return &block{
+		contents:         bytes[:restartsBeginOffset],
+		restarts:         restarts,
+		prefixCompressed: true,
+	}, nil
+}
+
+func (block *block) entries() ([]blockEntry, error) {
+	if !block.prefixCompressed {
+		return decodePersistentSSTableSlices(block.contents)
+	}
+	var entries []blockEntry
+	previousKey := model.NilSlice()
+	for offset := 0; offset < len(block.contents); {
+		entry, nextOffset, err := block.decodeEntry(offset, previousKey)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		offset, previousKey = nextOffset, entry.key
+	}
+	return entries, nil
+}
+
+// get returns the newest version of the key with a sequence less than or equal to the sequence.
+// It binary searches the restart points for the last one with a key smaller than the key and scans the entries after it,
+// the versions of the key may begin before the first restart point with the key
+func (block *block) get(key model.Slice, sequence uint64, keyComparator comparator.KeyComparator) (blockEntry, bool, error) {
+	if !block.prefixCompressed {
+		entries, err := block.entries()
+		if err != nil {
+			return blockEntry{}, false, err
+		}
+		for index := search(entries, key, keyComparator); index < len(entries) && keyComparator.Compare(entries[index].key, key) == 0; index++ {
+			if entries[index].sequence <= sequence {
+				return entries[index], true, nil
+			}
+		}
+		return blockEntry{}, false, nil
+	}
+	var err error
+	restartIndex := sort.Search(len(block.restarts), func(index int) bool {
+		entry, _, decodeErr := block.decodeEntry(int(block.restarts[index]), model.NilSlice())
+		if decodeErr != nil {
+			err = decodeErr
+			return true
+		}
+		return keyComparator.Compare(entry.key, key) >= 0
+	})
+	if err != nil {
+		return blockEntry{}, false, err
+	}
+	if restartIndex > 0 {
+		restartIndex = restartIndex - 1
+	}
+	previousKey := model.NilSlice()
+	for offset := int(block.restarts[restartIndex]); offset < len(block.contents); {
+		entry, nextOffset, err := block.decodeEntry(offset, previousKey)
+		if err != nil {
+			return blockEntry{}, false, err
+		}
+		comparison := keyComparator.Compare(entry.key, key)
+		if comparison > 0 {
+			return blockEntry{}, false, nil
+		}
+		if comparison == 0 && entry.sequence <= sequence {
+			return entry, true, nil
+		}
+		offset, previousKey = nextOffset, entry.key
+	}
+	return blockEntry{}, false, nil
+}
+
+func (block *block) decodeEntry(offset int, previousKey model.Slice) (blockEntry, int, error) {
+	contents := block.contents
+	malformed := func() (blockEntry, int, error) {
+		return blockEntry{}, 0, errors.New(fmt.Sprintf("malformed ssTable block, invalid entry at offset %v", offset))
+	}
+	readUvarint := func() (int, bool) {
+		value, size := binary.Uvarint(contents[offset:])
+		if size <= 0 {
+			return 0, false
+		}
+		offset = offset + size
+		return int(value), true
+	}
+	shared, ok := readUvarint()
+	if !ok {
+		return malformed()
+	}
+	nonShared, ok := readUvarint()
+	if !ok {
+		return malformed()
+	}
+	valueSize, ok := readUvarint()
+	if !ok {
+		return malformed()
+	}
+	sequence, sequenceSize := binary.Uvarint(contents[offset:])
+	if sequenceSize <= 0 {
+		return malformed()
+	}
+	offset = offset + sequenceSize
+	if shared > previousKey.Size() || nonShared < 0 || valueSize < 0 || offset+1+nonShared+valueSize > len(contents) {
+		return malformed()
+	}
+	kind := contents[offset]
+	offset = offset + 1
+
+	key := make([]byte, shared+nonShared)
+	copy(key, previousKey.GetRawContent()[:shared])
+	copy(key[shared:], contents[offset:offset+nonShared])
+	offset = offset + nonShared
+
+	value := contents[offset : offset+valueSize]
+	return blockEntry{key: model.NewSlice(key), value: model.NewSlice(value), kind: kind, sequence: sequence}, offset + valueSize, nil
+}
+
+// decodePersistentSSTableSlices decodes a block of a legacy ssTable, legacy ssTables have no deleted keys
+func decodePersistentSSTableSlices(bytes []byte) ([]blockEntry, error) {
+	var entries []blockEntry
+	for offset := 0; offset < len(bytes); {
+		if offset+int(reservedTotalSize) > len(bytes) {
+			return nil, errors.New(fmt.Sprintf("malformed ssTable block, %v bytes left at offset %v", len(bytes)-offset, offset))
+		}
+		totalSize := int(ActualTotalSize(bytes[offset:]))
+		headerSize := int(reservedTotalSize + reservedKeySize)
+		if totalSize < headerSize || offset+totalSize > len(bytes) {
+			return nil, errors.New(fmt.Sprintf("malformed ssTable block, invalid entry size %v at offset %v", totalSize, offset))
+		}
+		if keySize := int(bigEndian.Uint32(bytes[offset+int(reservedTotalSize):])); keySize > totalSize-headerSize {
+			return nil, errors.New(fmt.Sprintf("malformed ssTable block, invalid key size %v at offset %v", keySize, offset))
+		}
+		key, value := NewPersistentSSTableSliceKeyValuePair(bytes[offset : offset+totalSize])
+		entries = append(entries, blockEntry{key: key.GetSlice(), value: value.GetSlice(), kind: kindPut})
+		offset = offset + totalSize
+	}
+	return entries, nil
+}
+
+// search returns the index of the first entry with a key greater than or equal to the key
+func search(entries []blockEntry, key model.Slice, keyComparator comparator.KeyComparator) int {
+	return sort.Search(len(entries), func(index int) bool {
+		return keyComparator.Compare(entries[index].key, key) >= 0
+	})
+}
+
+func sharedPrefixSize(first, second []byte) int {
+	size := 0
+	for size < len(first) && size < len(second) && first[size] == second[size] {
+		size = size + 1
+	}
+	return size
+}
@@ -0,0 +1,369 @@
+package sst
+
+import (
+	"log"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/iterator"
+	"storage-engine-workshop/storage/manifest"
+	"storage-engine-workshop/storage/snapshot"
+)
+
+type CompactionOptions struct {
+	Level0FileCountTrigger int
+	LevelSizeRatio         int
+	Level1MaxSizeBytes     int64
+	MaxLevels              int
+	TargetFileSizeBytes    int64
+}
+
+func DefaultCompactionOptions() CompactionOptions {
+	return CompactionOptions{
+		Level0FileCountTrigger: 4,
+		LevelSizeRatio:         10,
+		Level1MaxSizeBytes:     10 * 1024 * 1024,
+		MaxLevels:              7,
+		TargetFileSizeBytes:    2 * 1024 * 1024,
+	}
+}
+
+func (options CompactionOptions) maxSizeBytesOf(level int) int64 {
+	maxSizeBytes := options.Level1MaxSizeBytes
+	for current := 1; current < level; current++ {
+		maxSizeBytes = maxSizeBytes * int64(options.LevelSizeRatio)
+	}
+	return maxSizeBytes
+}
+
+// compaction merges the inputs from a level with the overlapping ssTables of the next level (targetLevel)
+type compaction struct {
+	level        int
+	targetLevel  int
+	inputs       []*SSTable
+	targetInputs []*SSTable
+}
+
+// compactor runs compactions in a single background goroutine, one compaction at a time
+type compactor struct {
+	ssTables       *SSTables
+	options        CompactionOptions
+	keyComparator  comparator.KeyComparator
+	snapshots      *snapshot.Snapshots
+	trigger        chan struct{}
+	stopChannel    chan struct{}
+	stoppedChannel chan struct{}
+	compactPointer map[int]int
+}
+
+func newCompactor(ssTables *SSTables, options CompactionOptions, keyComparator comparator.KeyComparator, snapshots *snapshot.Snapshots) *compactor {
+	return &compactor{
+		ssTables:       ssTables,
+		options:        options,
+		keyComparator:  keyComparator,
+		snapshots:      snapshots,
+		trigger:        make(chan struct{}, 1),
+		stopChannel:    make(chan struct{}),
+		stoppedChannel: make(chan struct{}),
+		compactPointer: make(map[int]int),
+	}
+}
+
+func (compactor *compactor) start() {
+	compactAll := func() {
+		for {
+			select {
+			case <-compactor.stopChannel:
+				return
+			default:
+			}
+			compacted, err := compactor.compact()
+			if err != nil {
+				log.Default().Println("Error while compacting ssTables " + err.Error())
+				return
+			}
+			if !compacted {
+				return
+			}
+		}
+	}
+	go func() {
+		defer close(compactor.stoppedChannel)
+		for {
+			select {
+			case <-compactor.trigger:
+				compactAll()
+			case <-compactor.stopChannel:
+				return
+			}
+		}
+	}()
+}
+
+// stop waits for the running compaction to finish and stops compacting, the later signals are ignored
+func (compactor *compactor) stop() {
+	close(compactor.stopChannel)
+	<-compactor.stoppedChannel
+}
+
+func (compactor *compactor) signal() {
+	select {
+	case compactor.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// compact runs a single compaction if any level needs one and returns true if it did
+func (compactor *compactor) compact() (bool, error) {
+	compaction := compactor.pick()
+	if compaction == nil {
+		return false, nil
+	}
+	outputs, err := compactor.merge(compaction)
+	if err != nil {
+		return false, err
+	}
+	return true, compactor.install(compaction, outputs)
+}
+
+func (compactor *compactor) pick() *compaction {
+	ssTables := compactor.ssTables
+	ssTables.lock.RLock()
+	defer ssTables.lock.RUnlock()
+
+	overlappingIn := func(level int, inputs []*SSTable) []*SSTable {
+		if level >= len(ssTables.levels) {
+			return nil
+		}
+		smallestKey, largestKey := compactor.keyRangeOf(inputs)
+		var overlapping []*SSTable
+		for _, table := range ssTables.levels[level] {
+			if table.overlaps(smallestKey, largestKey, compactor.keyComparator) {
+				overlapping = append(overlapping, table)
+			}
+		}
+		return overlapping
+	}
+	levelSize := func(level int) int64 {
+		var size int64
+		for _, table := range ssTables.levels[level] {
+			size = size + table.size
+		}
+		return size
+	}
+
+	if len(ssTables.levels[0]) >= compactor.options.Level0FileCountTrigger {
+		inputs := append([]*SSTable{}, ssTables.levels[0]...)
+		return &compaction{level: 0, targetLevel: 1, inputs: inputs, targetInputs: overlappingIn(1, inputs)}
+	}
+	for level := 1; level < len(ssTables.levels) && level < compactor.options.MaxLevels-1; level++ {
+		tables := ssTables.levels[level]
+		if len(tables) == 0 || levelSize(level) <= compactor.options.maxSizeBytesOf(level) {
+			continue
+		}
+		index := compactor.compactPointer[level] % len(tables)
+		compactor.compactPointer[level] = index + 1
+
+		inputs := []*SSTable{tables[index]}
+		return &compaction{level: level, targetLevel: level + 1, inputs: inputs, targetInputs: overlappingIn(level+1, inputs)}
+	}
+	return nil
+}
+
+func (compactor *compactor) merge(compaction *compaction) ([]*SSTable, error) {
+	var ssTableIterators []*SSTableIterator
+	closeAll := func() {
+		for _, ssTableIterator := range ssTableIterators {
+			ssTableIterator.Close()
+		}
+	}
+	defer closeAll()
+
+	//iterators are ordered newest first, level 0 inputs were appended in the order of their creation
+	var newestFirst []*SSTable
+	for index := len(compaction.inputs) - 1; index >= 0; index-- {
+		newestFirst = append(newestFirst, compaction.inputs[index])
+	}
+	newestFirst = append(newestFirst, compaction.targetInputs...)
+
+	var iterators []iterator.Iterator
+	for _, table := range newestFirst {
+		ssTableIterator := table.newIteratorOfAllVersions(compactor.keyComparator)
+		ssTableIterators = append(ssTableIterators, ssTableIterator)
+		iterators = append(iterators, ssTableIterator)
+	}
+
+	//versions newer than the oldest snapshot are kept, along with the newest version which the oldest snapshot sees
+	dropTombstones := compactor.isBottomMost(compaction.targetLevel)
+	oldestSnapshotSequence := compactor.snapshots.OldestSequence()
+	mergedIterator := iterator.NewMergedIteratorKeepingVersions(iterators, compactor.keyComparator)
+	mergedIterator.Seek(model.NilSlice())
+
+	var outputs []*SSTable
+	//the outputs written before a failure are never recorded in the manifest, they are removed instead of being left as orphans
+	releaseOutputs := func() {
+		for _, output := range outputs {
+			if err := output.release(); err != nil {
+				log.Default().Println("Error while releasing the ssTable " + err.Error())
+			}
+		}
+	}
+	var keyValuePairs []model.KeyValuePair
+	var size int64
+	var previousKey model.Slice
+	hasPreviousKey, olderVersionsHidden := false, false
+	for ; mergedIterator.IsValid(); mergedIterator.Next() {
+		if !hasPreviousKey || compactor.keyComparator.Compare(mergedIterator.Key(), previousKey) != 0 {
+			previousKey, hasPreviousKey, olderVersionsHidden = mergedIterator.Key(), true, false
+			//the versions of a key are written to a single ssTable, keeping the key ranges of the ssTables in a level disjoint
+			if size >= compactor.options.TargetFileSizeBytes {
+				output, err := compactor.write(keyValuePairs, compaction.targetLevel)
+				if err != nil {
+					releaseOutputs()
+					return nil, err
+				}
+				outputs = append(outputs, output)
+				keyValuePairs, size = nil, 0
+			}
+		}
+		if olderVersionsHidden {
+			continue
+		}
+		if mergedIterator.Sequence() <= oldestSnapshotSequence {
+			olderVersionsHidden = true
+			if mergedIterator.IsDeleted() && dropTombstones {
+				continue
+			}
+		}
+		//values in the value log are not rewritten by compaction, only their pointers are
+		keyValuePair := model.KeyValuePair{
+			Key:          mergedIterator.Key(),
+			Value:        mergedIterator.Value(),
+			Deleted:      mergedIterator.IsDeleted(),
+			ValuePointer: mergedIterator.IsValuePointer(),
+			Sequence:     mergedIterator.Sequence(),
+		}
+		keyValuePairs = append(keyValuePairs, keyValuePair)
+		size = size + int64(keyValuePair.Key.Size()+keyValuePair.Value.Size())
+	}
+	for _, ssTableIterator := range ssTableIterators {
+		if err := ssTableIterator.Err(); err != nil {
+			releaseOutputs()
+			return nil, err
+		}
+	}
+	if len(keyValuePairs) > 0 {
+		output, err := compactor.write(keyValuePairs, compaction.targetLevel)
+		if err != nil {
+			releaseOutputs()
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func (compactor *compactor) write(keyValuePairs []model.KeyValuePair, level int) (*SSTable, error) {
+	ssTables := compactor.ssTables
+	ssTables.lock.Lock()
+	fileId := ssTables.nextFileId
+	ssTables.nextFileId = ssTables.nextFileId + 1
+	ssTables.lock.Unlock()
+
+	ssTable, err := newSSTableWith(keyValuePairs, ssTables.bloomFilters, ssTables.directory, fileId, level)
+	if err != nil {
+		return nil, err
+	}
+	//the ssTable is written with the compression codec and the key comparator shared by all the ssTables
+	ssTables.share(ssTable)
+	if err := ssTable.Write(); err != nil {
+		_ = ssTable.release()
+		return nil, err
+	}
+	return ssTable, nil
+}
+
+// install records the compaction in the manifest, swaps its inputs with its outputs and releases the inputs
+func (compactor *compactor) install(compaction *compaction, outputs []*SSTable) error {
+	ssTables := compactor.ssTables
+	without := func(tables []*SSTable, removals []*SSTable) []*SSTable {
+		remaining := make([]*SSTable, 0, len(tables))
+		for _, table := range tables {
+			removed := false
+			for _, removal := range removals {
+				if table == removal {
+					removed = true
+					break
+				}
+			}
+			if !removed {
+				remaining = append(remaining, table)
+			}
+		}
+		return remaining
+	}
+
+	edit := manifest.NewEdit()
+	for _, table := range append(compaction.inputs, compaction.targetInputs...) {
+		edit.RemoveTable(table.fileId)
+	}
+	for _, output := range outputs {
+		edit.AddTable(output.fileId, output.level, int(output.version))
+	}
+	ssTables.lock.RLock()
+	edit.WithNextFileId(ssTables.nextFileId)
+	ssTables.lock.RUnlock()
+
+	err := ssTables.syncDirectories()
+	if err == nil {
+		err = ssTables.manifest.Apply(edit)
+	}
+	if err != nil {
+		for _, output := range outputs {
+			_ = output.release()
+		}
+		return err
+	}
+
+	ssTables.lock.Lock()
+	for len(ssTables.levels) <= compaction.targetLevel {
+		ssTables.levels = append(ssTables.levels, []*SSTable{})
+	}
+	ssTables.levels[compaction.level] = without(ssTables.levels[compaction.level], compaction.inputs)
+	ssTables.levels[compaction.targetLevel] = append(without(ssTables.levels[compaction.targetLevel], compaction.targetInputs), outputs...)
+	ssTables.sortLevel(compaction.targetLevel, compactor.keyComparator)
+	ssTables.lock.Unlock()
+
+	for _, table := range append(compaction.inputs, compaction.targetInputs...) {
+		if err := table.release(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBottomMost returns true if no level below the target level has any ssTable, tombstones can be dropped then
+func (compactor *compactor) isBottomMost(targetLevel int) bool {
+	ssTables := compactor.ssTables
+	ssTables.lock.RLock()
+	defer ssTables.lock.RUnlock()
+
+	for level := targetLevel + 1; level < len(ssTables.levels); level++ {
+		if len(ssTables.levels[level]) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (compactor *compactor) keyRangeOf(tables []*SSTable) (model.Slice, model.Slice) {
+	smallestKey, largestKey := tables[0].smallestKey, tables[0].largestKey
+	for _, table := range tables[1:] {
+		if compactor.keyComparator.Compare(table.smallestKey, smallestKey) < 0 {
+			smallestKey = table.smallestKey
+		}
+		if compactor.keyComparator.Compare(table.largestKey, largestKey) > 0 {
+			largestKey = table.largestKey
+		}
+	}
+	return smallestKey, largestKey
+}
@@ -0,0 +1,312 @@
+package sst
+
+import (
+	"fmt"
+	"os"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/memory"
+	"storage-engine-workshop/storage/snapshot"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func compactionOptions() CompactionOptions {
+	return CompactionOptions{
+		Level0FileCountTrigger: 2,
+		LevelSizeRatio:         10,
+		Level1MaxSizeBytes:     1024 * 1024,
+		MaxLevels:              3,
+		TargetFileSizeBytes:    1024 * 1024,
+	}
+}
+
+func flush(ssTables *SSTables, memTable *memory.MemTable) {
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	ssTables.AllowSearchIn(ssTable)
+}
+
+func TestCompactsLevel0SSTablesIntoLevel1(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	older := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	older.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	older.Put(model.NewSlice([]byte("PMEM")), model.NewSlice([]byte("Persistent memory")))
+	flush(ssTables, older)
+
+	newer := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	newer.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk drive")))
+	newer.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+	flush(ssTables, newer)
+
+	compacted, err := newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshot.NewSnapshots()).compact()
+	if err != nil || !compacted {
+		t.Fatalf("Expected level 0 to be compacted, received compacted %v and error %v", compacted, err)
+	}
+	if len(ssTables.levels[0]) != 0 || len(ssTables.levels[1]) != 1 {
+		t.Fatalf("Expected 0 ssTables in level 0 and 1 in level 1, received %v and %v", len(ssTables.levels[0]), len(ssTables.levels[1]))
+	}
+	expected := map[string]string{"HDD": "Hard disk drive", "PMEM": "Persistent memory", "SDD": "Solid state drive"}
+	for key, value := range expected {
+		if getResult := ssTables.Get(model.NewSlice([]byte(key)), comparator.StringKeyComparator{}); getResult.Value.AsString() != value {
+			t.Fatalf("Expected %v, received %v", value, getResult.Value.AsString())
+		}
+	}
+}
+
+func TestCompressesTheBlocksOfCompactedSSTablesWithTheCompressionCodec(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTablesWith(directory, nil, SnappyCompression{}, comparator.StringKeyComparator{})
+	defer os.RemoveAll(directory)
+
+	for _, keyPrefix := range []string{"HDD", "SSD"} {
+		memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		for count := 1; count <= 100; count++ {
+			memTable.Put(model.NewSlice([]byte(fmt.Sprintf("%v-%03d", keyPrefix, count))), model.NewSlice([]byte(fmt.Sprintf("Value-%0100d", count))))
+		}
+		flush(ssTables, memTable)
+	}
+	compacted, err := newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshot.NewSnapshots()).compact()
+	if err != nil || !compacted {
+		t.Fatalf("Expected level 0 to be compacted, received compacted %v and error %v", compacted, err)
+	}
+	output := ssTables.levels[1][0]
+	for _, handle := range output.blockHandles {
+		//a block ends with the compression codec id followed by its checksum
+		codecId := make([]byte, reservedCodecIdSize)
+		_, _ = output.store.ReadAt(codecId, handle.offset+int64(handle.size)-reservedChecksumSize-reservedCodecIdSize)
+		if codecId[0] != snappyCompressionId {
+			t.Fatalf("Expected the block at offset %v to be compressed with codec id %v, received %v", handle.offset, snappyCompressionId, codecId[0])
+		}
+	}
+}
+
+func TestDropsTombstonesWhileCompactingIntoTheBottomMostLevel(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	older := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	older.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	older.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+	flush(ssTables, older)
+
+	newer := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	newer.Delete(model.NewSlice([]byte("HDD")))
+	flush(ssTables, newer)
+
+	_, _ = newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshot.NewSnapshots()).compact()
+
+	var keys []string
+	ssTableIterator, _ := ssTables.levels[1][0].NewIterator(comparator.StringKeyComparator{})
+	for ssTableIterator.Seek(model.NilSlice()); ssTableIterator.IsValid(); ssTableIterator.Next() {
+		keys = append(keys, ssTableIterator.Key().AsString())
+	}
+	ssTableIterator.Close()
+	if len(keys) != 1 || keys[0] != "SDD" {
+		t.Fatalf("Expected only the key %v to be present after compaction, received %v", "SDD", keys)
+	}
+	if getResult := ssTables.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Exists {
+		t.Fatalf("Expected key %v to be missing after compaction, but was present", "HDD")
+	}
+}
+
+func TestKeepsTombstonesWhileCompactingAboveANonEmptyLevel(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	bottom := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	bottom.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	ssTable, _ := newSSTableWith(bottom.AllKeyValues(), ssTables.bloomFilters, ssTables.directory, 100, 2)
+	_ = ssTable.Write()
+	ssTables.addToLevel(ssTable)
+
+	deleted := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	deleted.Delete(model.NewSlice([]byte("HDD")))
+	flush(ssTables, deleted)
+	flush(ssTables, deleted)
+
+	_, _ = newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshot.NewSnapshots()).compact()
+
+	if getResult := ssTables.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Exists || !getResult.Deleted {
+		t.Fatalf("Expected key %v to remain deleted after compaction, received %v", "HDD", getResult.Value.AsString())
+	}
+}
+
+func TestReloadsCompactedSSTablesInTheirLevelSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	for _, value := range []string{"Hard disk", "Hard disk drive"} {
+		memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte(value)))
+		flush(ssTables, memTable)
+	}
+	_, _ = newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshot.NewSnapshots()).compact()
+
+	ssTablesAfterRestart, _ := NewSSTables(directory)
+	if len(ssTablesAfterRestart.levels[0]) != 0 || len(ssTablesAfterRestart.levels[1]) != 1 {
+		t.Fatalf("Expected 0 ssTables in level 0 and 1 in level 1, received %v and %v", len(ssTablesAfterRestart.levels[0]), len(ssTablesAfterRestart.levels[1]))
+	}
+	if ssTablesAfterRestart.nextFileId != 4 {
+		t.Fatalf("Expected next file id to be %v, received %v", 4, ssTablesAfterRestart.nextFileId)
+	}
+	if getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+}
+
+// lengthFirstKeyComparator orders shorter keys first, keys of the same length are ordered byte wise
+type lengthFirstKeyComparator struct {
+}
+
+func (keyComparator lengthFirstKeyComparator) Compare(one model.Slice, other model.Slice) int {
+	if one.Size() != other.Size() {
+		return one.Size() - other.Size()
+	}
+	return comparator.ByteWiseComparator{}.Compare(one, other)
+}
+
+func TestReloadsCompactedSSTablesOrderedByTheKeyComparatorSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTablesWith(directory, nil, NoCompression{}, lengthFirstKeyComparator{})
+	defer os.RemoveAll(directory)
+
+	for _, prefix := range []string{"Older", "Newer"} {
+		memTable := memory.NewMemTable(10, lengthFirstKeyComparator{})
+		for count := 1; count <= 50; count++ {
+			memTable.Put(model.NewSlice([]byte(fmt.Sprintf("Key-%v", count))), model.NewSlice([]byte(fmt.Sprintf("%v-%0100d", prefix, count))))
+		}
+		flush(ssTables, memTable)
+	}
+	options := compactionOptions()
+	options.TargetFileSizeBytes = 300
+	_, _ = newCompactor(ssTables, options, lengthFirstKeyComparator{}, snapshot.NewSnapshots()).compact()
+
+	ssTablesAfterRestart, _ := NewSSTablesWith(directory, nil, NoCompression{}, lengthFirstKeyComparator{})
+	if len(ssTablesAfterRestart.levels[1]) <= 1 {
+		t.Fatalf("Expected multiple ssTables in level 1, received %v", len(ssTablesAfterRestart.levels[1]))
+	}
+	for count := 1; count <= 50; count++ {
+		key := model.NewSlice([]byte(fmt.Sprintf("Key-%v", count)))
+		if getResult := ssTablesAfterRestart.Get(key, lengthFirstKeyComparator{}); getResult.Value.AsString() != fmt.Sprintf("Newer-%0100d", count) {
+			t.Fatalf("Expected %v, received %v", fmt.Sprintf("Newer-%0100d", count), getResult.Value.AsString())
+		}
+	}
+}
+
+func TestDoesNotCompactLevel0BelowTheTrigger(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	flush(ssTables, memTable)
+
+	compacted, _ := newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshot.NewSnapshots()).compact()
+	if compacted {
+		t.Fatalf("Expected level 0 with a single ssTable not to be compacted")
+	}
+}
+
+func TestKeepsTheVersionsVisibleToALiveSnapshotWhileCompacting(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	older := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	older.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk")), Sequence: 1})
+	older.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("SDD")), Value: model.NewSlice([]byte("Solid state")), Sequence: 1})
+	flush(ssTables, older)
+
+	newer := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	newer.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk drive")), Sequence: 2})
+	newer.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("SDD")), Deleted: true, Sequence: 3})
+	flush(ssTables, newer)
+
+	snapshots := snapshot.NewSnapshots()
+	liveSnapshot := snapshots.Take(func() uint64 { return 1 })
+	_, _ = newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshots).compact()
+
+	if len(ssTables.levels[0]) != 0 || len(ssTables.levels[1]) != 1 {
+		t.Fatalf("Expected 0 ssTables in level 0 and 1 in level 1, received %v and %v", len(ssTables.levels[0]), len(ssTables.levels[1]))
+	}
+	if getResult := ssTables.GetUnresolved(model.NewSlice([]byte("HDD")), liveSnapshot.Sequence(), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", getResult.Value.AsString())
+	}
+	if getResult := ssTables.GetUnresolved(model.NewSlice([]byte("SDD")), liveSnapshot.Sequence(), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Solid state" {
+		t.Fatalf("Expected %v, received %v", "Solid state", getResult.Value.AsString())
+	}
+	if getResult := ssTables.Get(model.NewSlice([]byte("SDD")), comparator.StringKeyComparator{}); getResult.Exists {
+		t.Fatalf("Expected key %v to be deleted, but was present", "SDD")
+	}
+
+	liveSnapshot.Release()
+	for sequence := uint64(4); sequence <= 5; sequence++ {
+		newest := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		newest.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk drive")), Sequence: sequence})
+		flush(ssTables, newest)
+	}
+	_, _ = newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshots).compact()
+
+	if getResult := ssTables.GetUnresolved(model.NewSlice([]byte("HDD")), 1, comparator.StringKeyComparator{}); getResult.Exists {
+		t.Fatalf("Expected the version of %v at sequence 1 to be dropped after the snapshot is released, received %v", "HDD", getResult.Value.AsString())
+	}
+	if getResult := ssTables.GetUnresolved(model.NewSlice([]byte("HDD")), 4, comparator.StringKeyComparator{}); getResult.Exists {
+		t.Fatalf("Expected the version of %v at sequence 4 to be dropped after the snapshot is released, received %v", "HDD", getResult.Value.AsString())
+	}
+}
+
+func TestKeepsTheVersionsVisibleToSnapshotsTakenConcurrentlyWhileCompacting(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	const versions = 20
+	for sequence := uint64(1); sequence <= versions; sequence++ {
+		memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		memTable.PutKeyValuePair(model.KeyValuePair{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte(fmt.Sprintf("Version-%v", sequence))), Sequence: sequence})
+		flush(ssTables, memTable)
+	}
+
+	snapshots := snapshot.NewSnapshots()
+	var lastSequence uint64 = 1
+	currentSequence := func() uint64 {
+		return atomic.LoadUint64(&lastSequence)
+	}
+	var taken []*snapshot.Snapshot
+	var takenLock sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(2 * versions)
+	for count := 1; count <= versions; count++ {
+		go func() {
+			defer wg.Done()
+			liveSnapshot := snapshots.Take(currentSequence)
+			takenLock.Lock()
+			taken = append(taken, liveSnapshot)
+			takenLock.Unlock()
+		}()
+		go func() {
+			defer wg.Done()
+			if sequence := atomic.LoadUint64(&lastSequence); sequence < versions {
+				atomic.CompareAndSwapUint64(&lastSequence, sequence, sequence+1)
+			}
+		}()
+	}
+	wg.Wait()
+	_, _ = newCompactor(ssTables, compactionOptions(), comparator.StringKeyComparator{}, snapshots).compact()
+
+	for _, liveSnapshot := range taken {
+		getResult := ssTables.GetUnresolved(model.NewSlice([]byte("HDD")), liveSnapshot.Sequence(), comparator.StringKeyComparator{})
+		if expected := fmt.Sprintf("Version-%v", liveSnapshot.Sequence()); getResult.Value.AsString() != expected {
+			t.Fatalf("Expected %v, received %v", expected, getResult.Value.AsString())
+		}
+	}
+}
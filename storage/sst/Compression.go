@@ -0,0 +1,58 @@
+package sst
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/snappy"
+)
+
+const (
+	noCompressionId     byte = 0
+	snappyCompressionId byte = 1
+)
+
+// CompressionCodec compresses the blocks of an ssTable.
+// The id of the codec is recorded in each block, so the codec must keep its id across releases
+type CompressionCodec interface {
+	Id() byte
+	Compress(bytes []byte) ([]byte, error)
+	Decompress(bytes []byte) ([]byte, error)
+}
+
+type NoCompression struct{}
+
+type SnappyCompression struct{}
+
+func (noCompression NoCompression) Id() byte {
+	return noCompressionId
+}
+
+func (noCompression NoCompression) Compress(bytes []byte) ([]byte, error) {
+	return bytes, nil
+}
+
+func (noCompression NoCompression) Decompress(bytes []byte) ([]byte, error) {
+	return bytes, nil
+}
+
+func (snappyCompression SnappyCompression) Id() byte {
+	return snappyCompressionId
+}
+
+func (snappyCompression SnappyCompression) Compress(bytes []byte) ([]byte, error) {
+	return snappy.Encode(nil, bytes), nil
+}
+
+func (snappyCompression SnappyCompression) Decompress(bytes []byte) ([]byte, error) {
+	return snappy.Decode(nil, bytes)
+}
+
+func compressionCodecWith(id byte) (CompressionCodec, error) {
+	switch id {
+	case noCompressionId:
+		return NoCompression{}, nil
+	case snappyCompressionId:
+		return SnappyCompression{}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported compression codec %v in ssTable block", id))
+}
@@ -0,0 +1,31 @@
+package sst
+
+import "fmt"
+
+// CorruptionError is returned when the contents of an ssTable, or of its bloom filter, can not be decoded or fail their checksum.
+// The file needs to be restored
+type CorruptionError struct {
+	FileId   int
+	FileName string
+	Offset   int64
+	Reason   string
+}
+
+// IOError is returned when an ssTable can not be read, the read may succeed if retried
+type IOError struct {
+	FileName string
+	Offset   int64
+	Err      error
+}
+
+func (corruptionError *CorruptionError) Error() string {
+	return fmt.Sprintf("corrupt file %v of ssTable %v at offset %v: %v", corruptionError.FileName, corruptionError.FileId, corruptionError.Offset, corruptionError.Reason)
+}
+
+func (ioError *IOError) Error() string {
+	return fmt.Sprintf("error while reading ssTable %v at offset %v: %v", ioError.FileName, ioError.Offset, ioError.Err.Error())
+}
+
+func (ioError *IOError) Unwrap() error {
+	return ioError.Err
+}
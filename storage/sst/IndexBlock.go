@@ -1,7 +1,10 @@
 package sst
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"sort"
 	"storage-engine-workshop/db/model"
 	"storage-engine-workshop/storage/comparator"
 	"unsafe"
@@ -11,91 +14,190 @@ var (
 	ReservedOffsetSize = unsafe.Sizeof(uint64(0))
 )
 
+const (
+	ssTableMagic   uint32 = 0x53535442
+	unknownVersion uint16 = 0
+	legacyVersion  uint16 = 1
+	currentVersion uint16 = 2
+	footerSize            = 18
+)
+
+// IndexBlock is the sparse index of an ssTable, it holds the last key, the offset and the size of each data block.
+// Layout of an ssTable: data blocks | index block | footer, where
+// each block is compressed with the codec whose id follows the block, followed by 4 bytes crc32c of the compressed block and the codec id (see compressBlock),
+// the index block is a block (see blockBuilder) with the last key of each data block mapped to uvarint block offset | uvarint block size and
+// the footer is 8 bytes index block offset | 4 bytes index block size | 4 bytes magic | 2 bytes version.
+// A legacy ssTable has one index entry (4 bytes keySize | 8 bytes offset | key) per key, followed by 8 bytes index block offset,
+// it is read as an ssTable with one block per key.
 type IndexBlock struct {
 	store *Store
 }
 
+type blockHandle struct {
+	lastKey model.Slice
+	offset  int64
+	size    uint32
+}
+
 func NewIndexBlock(store *Store) *IndexBlock {
 	return &IndexBlock{
 		store: store,
 	}
 }
 
-func (indexBlock *IndexBlock) Write(beginOffsetByKey []int64, blockBeginOffset int64, keyValuePairs []model.KeyValuePair) error {
-	offset, indexBlockBeginOffset := blockBeginOffset, blockBeginOffset
-
-	for index, keyValuePair := range keyValuePairs {
-		bytes := indexBlock.marshal(keyValuePair.Key, beginOffsetByKey[index])
-		//Assignment:SSTable:3:write the marshalled byte array that represents the key, in the file
-		fmt.Println(bytes)
-		bytesWritten := 0
-		var err error = nil
-		if err != nil {
-			return err
-		} else {
-			offset = offset + int64(bytesWritten)
-		}
+func (indexBlock *IndexBlock) Write(blockHandles []blockHandle, indexBlockBeginOffset int64, compressionCodec CompressionCodec) error {
+	builder, buffer := newBlockBuilder(), make([]byte, 2*binary.MaxVarintLen64)
+	for _, handle := range blockHandles {
+		size := binary.PutUvarint(buffer, uint64(handle.offset))
+		size = size + binary.PutUvarint(buffer[size:], uint64(handle.size))
+		builder.addEntry(handle.lastKey, model.NewSlice(buffer[:size]), kindPut, 0)
 	}
-	bytes := make([]byte, ReservedOffsetSize)
-	bigEndian.PutUint64(bytes, uint64(indexBlockBeginOffset))
+	bytes, err := compressBlock(builder.finish(), compressionCodec)
+	if err != nil {
+		return err
+	}
+	footer := make([]byte, footerSize)
+	bigEndian.PutUint64(footer, uint64(indexBlockBeginOffset))
+	bigEndian.PutUint32(footer[8:], uint32(len(bytes)))
+	bigEndian.PutUint32(footer[12:], ssTableMagic)
+	bigEndian.PutUint16(footer[16:], currentVersion)
 
-	_, err := indexBlock.store.WriteAt(bytes, offset)
+	_, err = indexBlock.store.WriteAt(append(bytes, footer...), indexBlockBeginOffset)
 	return err
 }
 
-func (indexBlock *IndexBlock) GetKeyOffset(key model.Slice, keyComparator comparator.KeyComparator) (int64, error) {
-	blockBytes, err := indexBlock.readIndexBlock()
+// Read returns the handles of all the data blocks ordered by their last key, along with the version of the ssTable.
+// The version which the ssTable is recorded with picks the reader, a footer which does not carry the version is corrupt.
+// The version of an ssTable recorded without one is detected from its footer, an ssTable without a footer is read as a legacy ssTable.
+// A footer or an index which can not be decoded is returned as a CorruptionError
+func (indexBlock *IndexBlock) Read(recordedVersion uint16) ([]blockHandle, uint16, error) {
+	size, err := indexBlock.store.Size()
 	if err != nil {
-		return -1, err
-	}
-	index := 0
-	for index < len(blockBytes) {
-		actualKeySize := bigEndian.Uint32(blockBytes[index:])
-		keyBeginIndex := index + int(reservedKeySize) + int(ReservedOffsetSize)
-		serializedKey := blockBytes[keyBeginIndex : keyBeginIndex+int(actualKeySize)]
-		if keyComparator.Compare(model.NewSlice(serializedKey), key) == 0 {
-			keyOffset := bigEndian.Uint64(blockBytes[(index + int(reservedKeySize)):])
-			return int64(keyOffset), nil
+		return nil, 0, err
+	}
+	if recordedVersion == legacyVersion {
+		return indexBlock.readLegacy(size)
+	}
+	if size < footerSize {
+		if recordedVersion == unknownVersion {
+			return indexBlock.readLegacy(size)
 		}
-		index = index + int(reservedKeySize) + int(ReservedOffsetSize) + int(actualKeySize)
+		return nil, 0, indexBlock.corruptionAt(0, errors.New(fmt.Sprintf("ssTable of %v bytes is smaller than its footer", size)))
+	}
+	footer := make([]byte, footerSize)
+	if _, err := indexBlock.store.ReadAt(footer, size-footerSize); err != nil {
+		return nil, 0, &IOError{FileName: indexBlock.store.file.Name(), Offset: size - footerSize, Err: err}
 	}
-	return -1, nil
+	if bigEndian.Uint32(footer[12:]) != ssTableMagic {
+		if recordedVersion == unknownVersion {
+			return indexBlock.readLegacy(size)
+		}
+		return nil, 0, indexBlock.corruptionAt(size-footerSize, errors.New("footer does not carry the ssTable magic"))
+	}
+	version := bigEndian.Uint16(footer[16:])
+	if recordedVersion != unknownVersion && version != recordedVersion {
+		return nil, 0, indexBlock.corruptionAt(size-footerSize, errors.New(fmt.Sprintf("footer carries version %v, the ssTable is recorded with version %v", version, recordedVersion)))
+	}
+	if version > currentVersion {
+		return nil, 0, errors.New(fmt.Sprintf("ssTable version %v is not supported, supported version is %v", version, currentVersion))
+	}
+	//the footer is trusted only if the index block which it points to ends where the footer begins
+	indexBlockBeginOffset, indexBlockSize := int64(bigEndian.Uint64(footer)), int64(bigEndian.Uint32(footer[8:]))
+	if indexBlockBeginOffset < 0 || indexBlockBeginOffset+indexBlockSize != size-footerSize {
+		return nil, 0, indexBlock.corruptionAt(size-footerSize, errors.New("malformed footer"))
+	}
+	bytes := make([]byte, indexBlockSize)
+	if _, err := indexBlock.store.ReadAt(bytes, indexBlockBeginOffset); err != nil {
+		return nil, 0, &IOError{FileName: indexBlock.store.file.Name(), Offset: indexBlockBeginOffset, Err: err}
+	}
+	blockHandles, err := indexBlock.decode(bytes)
+	if err != nil {
+		return nil, 0, indexBlock.corruptionAt(indexBlockBeginOffset, err)
+	}
+	return blockHandles, version, nil
 }
 
-func (indexBlock *IndexBlock) readIndexBlock() ([]byte, error) {
-	size, _ := indexBlock.store.Size()
-	offsetContainingIndexBegin := size - int64(ReservedOffsetSize)
-	_, err := indexBlock.store.SeekFromBeginning(offsetContainingIndexBegin)
+func (indexBlock *IndexBlock) corruptionAt(offset int64, err error) *CorruptionError {
+	return &CorruptionError{FileName: indexBlock.store.file.Name(), Offset: offset, Reason: err.Error()}
+}
+
+func (indexBlock *IndexBlock) decode(bytes []byte) ([]blockHandle, error) {
+	block, err := newBlock(bytes, currentVersion)
 	if err != nil {
 		return nil, err
 	}
-	indexBlockBeginOffsetBytes := make([]byte, int(ReservedOffsetSize))
-	_, err = indexBlock.store.ReadAt(indexBlockBeginOffsetBytes, offsetContainingIndexBegin)
+	entries, err := block.entries()
 	if err != nil {
 		return nil, err
 	}
-	indexBlockBeginOffset := bigEndian.Uint64(indexBlockBeginOffsetBytes[0:])
-	blockBytes := make([]byte, offsetContainingIndexBegin-int64(indexBlockBeginOffset))
-	_, err = indexBlock.store.ReadAt(blockBytes, int64(indexBlockBeginOffset))
-	if err != nil {
-		return nil, err
+	blockHandles := make([]blockHandle, 0, len(entries))
+	for _, entry := range entries {
+		value := entry.value.GetRawContent()
+		offset, offsetSize := binary.Uvarint(value)
+		if offsetSize <= 0 {
+			return nil, errors.New(fmt.Sprintf("malformed ssTable index in %v", indexBlock.store.file.Name()))
+		}
+		size, sizeSize := binary.Uvarint(value[offsetSize:])
+		if sizeSize <= 0 {
+			return nil, errors.New(fmt.Sprintf("malformed ssTable index in %v", indexBlock.store.file.Name()))
+		}
+		blockHandles = append(blockHandles, blockHandle{lastKey: entry.key, offset: int64(offset), size: uint32(size)})
 	}
-	return blockBytes, nil
+	return blockHandles, nil
 }
 
-func (indexBlock *IndexBlock) marshal(key model.Slice, keyBeginOffset int64) []byte {
-	actualTotalSize := uint64(reservedKeySize) + uint64(ReservedOffsetSize) + uint64(key.Size())
-
-	//The way index block is encoded is: 4 bytes for keySize | 8 bytes for offsetSize | Key content
-	bytes := make([]byte, actualTotalSize)
-	index := 0
-
-	bigEndian.PutUint32(bytes[index:], uint32(key.Size()))
-	index = index + int(reservedKeySize)
+func (indexBlock *IndexBlock) readLegacy(size int64) ([]blockHandle, uint16, error) {
+	blockHandles, err := indexBlock.readLegacyIndex(size)
+	return blockHandles, legacyVersion, err
+}
 
-	bigEndian.PutUint64(bytes[index:], uint64(keyBeginOffset))
-	index = index + int(ReservedOffsetSize)
+func (indexBlock *IndexBlock) readLegacyIndex(size int64) ([]blockHandle, error) {
+	offsetContainingIndexBegin := size - int64(ReservedOffsetSize)
+	if offsetContainingIndexBegin < 0 {
+		return nil, indexBlock.corruptionAt(0, errors.New(fmt.Sprintf("malformed ssTable of %v bytes", size)))
+	}
+	indexBlockBeginOffsetBytes := make([]byte, int(ReservedOffsetSize))
+	if _, err := indexBlock.store.ReadAt(indexBlockBeginOffsetBytes, offsetContainingIndexBegin); err != nil {
+		return nil, &IOError{FileName: indexBlock.store.file.Name(), Offset: offsetContainingIndexBegin, Err: err}
+	}
+	indexBlockBeginOffset := int64(bigEndian.Uint64(indexBlockBeginOffsetBytes))
+	if indexBlockBeginOffset > offsetContainingIndexBegin {
+		return nil, indexBlock.corruptionAt(offsetContainingIndexBegin, errors.New("malformed ssTable index offset"))
+	}
+	bytes := make([]byte, offsetContainingIndexBegin-indexBlockBeginOffset)
+	if _, err := indexBlock.store.ReadAt(bytes, indexBlockBeginOffset); err != nil {
+		return nil, &IOError{FileName: indexBlock.store.file.Name(), Offset: indexBlockBeginOffset, Err: err}
+	}
+	var blockHandles []blockHandle
+	for index := 0; index < len(bytes); {
+		entryHeaderSize := int(reservedKeySize) + int(ReservedOffsetSize)
+		if index+entryHeaderSize > len(bytes) {
+			return nil, indexBlock.corruptionAt(indexBlockBeginOffset+int64(index), errors.New("malformed ssTable index entry"))
+		}
+		keySize := int(bigEndian.Uint32(bytes[index:]))
+		offset := int64(bigEndian.Uint64(bytes[index+int(reservedKeySize):]))
+		if index+entryHeaderSize+keySize > len(bytes) {
+			return nil, indexBlock.corruptionAt(indexBlockBeginOffset+int64(index), errors.New("malformed ssTable index entry"))
+		}
+		key := bytes[index+entryHeaderSize : index+entryHeaderSize+keySize]
+		blockHandles = append(blockHandles, blockHandle{lastKey: model.NewSlice(key), offset: offset})
+		index = index + entryHeaderSize + keySize
+	}
+	//every key is a block of its own, which ends where the next one begins
+	for index := range blockHandles {
+		end := indexBlockBeginOffset
+		if index+1 < len(blockHandles) {
+			end = blockHandles[index+1].offset
+		}
+		blockHandles[index].size = uint32(end - blockHandles[index].offset)
+	}
+	return blockHandles, nil
+}
 
-	copy(bytes[index:], key.GetRawContent())
-	return bytes
+// blockIndexFor returns the index of the only block which may contain the key, the first block whose last key is greater
+// than or equal to the key. Returns the number of blocks if the key is greater than all the keys
+func blockIndexFor(blockHandles []blockHandle, key model.Slice, keyComparator comparator.KeyComparator) int {
+	return sort.Search(len(blockHandles), func(index int) bool {
+		return keyComparator.Compare(blockHandles[index].lastKey, key) >= 0
+	})
 }
@@ -12,6 +12,12 @@ var (
 	reservedKeySize   = unsafe.Sizeof(uint32(0))
 )
 
+const (
+	kindPut          byte = 0
+	kindDelete       byte = 1
+	kindValuePointer byte = 2
+)
+
 type PersistentSSTableSlice struct {
 	contents []byte
 }
@@ -77,3 +83,13 @@ func unmarshal(bytes []byte) (PersistentSSTableSlice, PersistentSSTableSlice) {
 
 	return PersistentSSTableSlice{contents: bytes[reservedKeySize:keyEndOffset]}, PersistentSSTableSlice{contents: bytes[keyEndOffset:]}
 }
+
+func kindOf(keyValuePair model.KeyValuePair) byte {
+	if keyValuePair.Deleted {
+		return kindDelete
+	}
+	if keyValuePair.ValuePointer {
+		return kindValuePointer
+	}
+	return kindPut
+}
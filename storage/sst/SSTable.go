@@ -3,108 +3,393 @@ package sst
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path"
 	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/cache"
 	"storage-engine-workshop/storage/comparator"
 	"storage-engine-workshop/storage/filter"
 	"storage-engine-workshop/storage/memory"
+	"storage-engine-workshop/storage/vlog"
 	"strconv"
+	"sync/atomic"
 )
 
 type SSTable struct {
 	store         *Store
 	keyValuePairs []model.KeyValuePair
 	bloomFilter   *filter.BloomFilter
+	bloomFilters  *filter.BloomFilters
+	valueLog      *vlog.ValueLog
+	blockCache    *cache.BlockCache
+	compression   CompressionCodec
+	keyComparator comparator.KeyComparator
+	blockHandles  []blockHandle
+	version       uint16
+	fileId        int
+	level         int
+	smallestKey   model.Slice
+	largestKey    model.Slice
+	size          int64
+	references    int32
 }
 
 func NewSSTableFrom(memTable *memory.MemTable, bloomFilters *filter.BloomFilters, directory string, fileId int) (*SSTable, error) {
-	store, err := NewStore(path.Join(directory, fmt.Sprintf("%v.sst", fileId)))
+	return newSSTableWith(memTable.AllKeyValues(), bloomFilters, directory, fileId, 0)
+}
+
+// NewSSTableFromFile reopens the ssTable, detecting its version from its footer
+func NewSSTableFromFile(bloomFilters *filter.BloomFilters, directory string, fileName string, fileId int, level int) (*SSTable, error) {
+	return NewSSTableFromFileOfVersion(bloomFilters, directory, fileName, fileId, level, unknownVersion)
+}
+
+// NewSSTableFromFileOfVersion reopens the ssTable which is recorded with the version, an ssTable whose footer does not carry the version is corrupt
+func NewSSTableFromFileOfVersion(bloomFilters *filter.BloomFilters, directory string, fileName string, fileId int, level int, version uint16) (*SSTable, error) {
+	bloomFilter, ok := bloomFilters.BloomFilterWith(strconv.Itoa(fileId))
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("no bloom filter found for ssTable with file id %v", fileId))
+	}
+	store, err := NewStore(path.Join(directory, fileName))
 	if err != nil {
 		return nil, err
 	}
-	bloomFilter, err := createBloomFilter(fileId, memTable.TotalKeys(), bloomFilters)
+	ssTable := &SSTable{
+		store:         store,
+		keyValuePairs: []model.KeyValuePair{},
+		bloomFilter:   bloomFilter,
+		bloomFilters:  bloomFilters,
+		fileId:        fileId,
+		level:         level,
+		version:       version,
+		references:    1,
+	}
+	if err := ssTable.loadIndex(); err != nil {
+		return nil, err
+	}
+	return ssTable, nil
+}
+
+func newSSTableWith(keyValuePairs []model.KeyValuePair, bloomFilters *filter.BloomFilters, directory string, fileId int, level int) (*SSTable, error) {
+	store, err := NewStore(path.Join(directory, ssTableFileName(fileId, level)))
 	if err != nil {
 		return nil, err
 	}
-	return &SSTable{
+	bloomFilter, err := createBloomFilter(fileId, len(keyValuePairs), bloomFilters)
+	if err != nil {
+		return nil, err
+	}
+	ssTable := &SSTable{
 		store:         store,
-		keyValuePairs: []model.KeyValuePair{}, //Assignment:SSTable:1:get all key value pairs
+		keyValuePairs: keyValuePairs,
 		bloomFilter:   bloomFilter,
-	}, nil
+		bloomFilters:  bloomFilters,
+		fileId:        fileId,
+		level:         level,
+		compression:   NoCompression{},
+		keyComparator: comparator.ByteWiseComparator{},
+		version:       currentVersion,
+		references:    1,
+	}
+	if len(keyValuePairs) > 0 {
+		ssTable.smallestKey, ssTable.largestKey = keyValuePairs[0].Key, keyValuePairs[len(keyValuePairs)-1].Key
+	}
+	return ssTable, nil
 }
 
 func (ssTable *SSTable) Write() error {
 	if len(ssTable.keyValuePairs) == 0 {
 		return errors.New("ssTable does not contain any key value pairs to write to " + ssTable.store.file.Name())
 	}
-	beginOffsetByKey, offset, err := ssTable.writeKeyValues()
+	blockHandles, offset, err := ssTable.writeDataBlocks()
 	if err != nil {
 		return err
 	}
 	indexBlock := NewIndexBlock(ssTable.store)
-	if err := indexBlock.Write(beginOffsetByKey, offset, ssTable.keyValuePairs); err != nil {
+	if err := indexBlock.Write(blockHandles, offset, ssTable.compression); err != nil {
 		return err
 	}
+	//the contents of the ssTable and its bloom filter are durable before the ssTable is recorded in the manifest, and the WAL it replaces is deleted.
+	//Their directory entries are made durable by SSTables before the manifest edit
 	if err := ssTable.store.Sync(); err != nil {
 		return errors.New("error while syncing the ssTable file " + ssTable.store.file.Name())
 	}
+	if err := ssTable.bloomFilter.Sync(); err != nil {
+		return errors.New("error while syncing the bloom filter file " + ssTable.bloomFilter.FileName())
+	}
+	size, err := ssTable.store.Size()
+	if err != nil {
+		return err
+	}
+	ssTable.size, ssTable.blockHandles = size, blockHandles
 	return nil
 }
 
+// Get returns the latest value of the key, reading it from the value log if the ssTable holds a pointer to it.
+// The result carries the error if the value can not be read from the value log
 func (ssTable *SSTable) Get(key model.Slice, keyComparator comparator.KeyComparator) model.GetResult {
-	NewIndexBlock(ssTable.store)
-	//Assignment:SSTable:4:get the offset of the key by using the newly created index block
-	var keyOffset int64 = 0
-	var err error
+	return ssTable.resolve(ssTable.getUnresolved(key, model.LatestSequence, keyComparator))
+}
+
+func (ssTable *SSTable) resolve(getResult model.GetResult) model.GetResult {
+	if !getResult.ValuePointer {
+		return getResult
+	}
+	key := getResult.Key
+	if ssTable.valueLog == nil {
+		return model.GetResult{Key: key, Exists: false, Err: errors.New("no value log is available to resolve the value pointer of key " + key.AsString())}
+	}
+	value, err := ssTable.valueLog.Resolve(getResult.Value)
 	if err != nil {
+		return model.GetResult{Key: key, Exists: false, Err: err}
+	}
+	return model.GetResult{Key: key, Value: value, Exists: true, Sequence: getResult.Sequence}
+}
+
+// getUnresolved reads the only data block which may contain the key, as per the sparse index.
+// It returns the newest version of the key with a sequence less than or equal to the sequence,
+// the result carries an IOError or a CorruptionError if the block can not be read
+func (ssTable *SSTable) getUnresolved(key model.Slice, sequence uint64, keyComparator comparator.KeyComparator) model.GetResult {
+	blockIndex := blockIndexFor(ssTable.blockHandles, key, keyComparator)
+	if blockIndex == len(ssTable.blockHandles) {
 		return model.GetResult{Key: key, Exists: false}
 	}
-	if keyOffset == -1 {
+	handle := ssTable.blockHandles[blockIndex]
+	block, err := ssTable.readBlock(handle)
+	if err != nil {
+		return model.GetResult{Key: key, Exists: false, Err: err}
+	}
+	entry, ok, err := block.get(key, sequence, keyComparator)
+	if err != nil {
+		return model.GetResult{Key: key, Exists: false, Err: ssTable.corruptionAt(handle.offset, err)}
+	}
+	if !ok {
 		return model.GetResult{Key: key, Exists: false}
 	}
+	if entry.kind == kindDelete {
+		return model.GetResult{Key: key, Exists: false, Deleted: true, Sequence: entry.sequence}
+	}
+	return model.GetResult{Key: key, Value: entry.value, Exists: true, ValuePointer: entry.kind == kindValuePointer, Sequence: entry.sequence}
+}
+
+func (ssTable *SSTable) NewIterator(keyComparator comparator.KeyComparator) (*SSTableIterator, error) {
+	return ssTable.newIteratorAt(model.LatestSequence, keyComparator), nil
+}
+
+// newIteratorAt returns an iterator over the newest version of each key with a sequence less than or equal to the sequence
+func (ssTable *SSTable) newIteratorAt(sequence uint64, keyComparator comparator.KeyComparator) *SSTableIterator {
+	ssTable.acquire()
+	return newSSTableIterator(ssTable, ssTable.blockHandles, sequence, keyComparator)
+}
+
+// newIteratorOfAllVersions returns an iterator over every version of every key, newest version of a key first
+func (ssTable *SSTable) newIteratorOfAllVersions(keyComparator comparator.KeyComparator) *SSTableIterator {
+	ssTableIterator := ssTable.newIteratorAt(model.LatestSequence, keyComparator)
+	ssTableIterator.allVersions = true
+	return ssTableIterator
+}
+
+func (ssTable *SSTable) Close() error {
+	return ssTable.store.Close()
+}
+
+func (ssTable *SSTable) isEmpty() bool {
+	return ssTable.size == 0
+}
+
+func (ssTable *SSTable) mayContain(key model.Slice, keyComparator comparator.KeyComparator) bool {
+	return keyComparator.Compare(key, ssTable.smallestKey) >= 0 && keyComparator.Compare(key, ssTable.largestKey) <= 0
+}
+
+func (ssTable *SSTable) overlaps(smallestKey, largestKey model.Slice, keyComparator comparator.KeyComparator) bool {
+	return keyComparator.Compare(ssTable.largestKey, smallestKey) >= 0 && keyComparator.Compare(ssTable.smallestKey, largestKey) <= 0
+}
+
+func (ssTable *SSTable) acquire() {
+	atomic.AddInt32(&ssTable.references, 1)
+}
+
+// release drops a reference, the ssTable and its bloom filter are deleted once it is neither searchable nor iterated
+func (ssTable *SSTable) release() error {
+	if atomic.AddInt32(&ssTable.references, -1) > 0 {
+		return nil
+	}
+	_ = ssTable.Close()
+	if err := os.Remove(ssTable.store.file.Name()); err != nil {
+		return err
+	}
+	return ssTable.bloomFilters.Delete(ssTable.bloomFilter)
+}
 
-	//Assignment:SSTable:5:read at the offset obtained after completing //Assignment:SSTable:4
-	var resultValue PersistentSSTableSlice
+// loadIndex keeps the sparse index in memory, the smallest key is read from the first data block.
+// A footer, an index or a first block which can not be decoded is returned as a CorruptionError carrying the file id
+func (ssTable *SSTable) loadIndex() error {
+	size, err := ssTable.store.Size()
 	if err != nil {
-		return model.GetResult{Key: key, Exists: false}
+		return err
+	}
+	ssTable.size = size
+	if ssTable.isEmpty() {
+		return nil
+	}
+	blockHandles, version, err := NewIndexBlock(ssTable.store).Read(ssTable.version)
+	if err != nil {
+		var corruptionError *CorruptionError
+		if errors.As(err, &corruptionError) {
+			corruptionError.FileId = ssTable.fileId
+		}
+		return err
+	}
+	ssTable.blockHandles, ssTable.version = blockHandles, version
+	if len(blockHandles) == 0 {
+		return nil
 	}
-	return model.GetResult{Key: key, Value: resultValue.GetSlice(), Exists: true}
+	block, err := ssTable.readBlock(blockHandles[0])
+	if err != nil {
+		return err
+	}
+	entries, err := block.entries()
+	if err != nil {
+		return ssTable.corruptionAt(blockHandles[0].offset, err)
+	}
+	if len(entries) > 0 {
+		ssTable.smallestKey, ssTable.largestKey = entries[0].key, blockHandles[len(blockHandles)-1].lastKey
+	}
+	return nil
 }
 
-func (ssTable *SSTable) readAt(offset int64) (PersistentSSTableSlice, PersistentSSTableSlice, error) {
-	bytes := make([]byte, int(reservedTotalSize))
-	_, err := ssTable.store.ReadAt(bytes, offset)
+// readBlock returns the block, from the block cache if the block is cached.
+// It returns an IOError if the block can not be read and a CorruptionError if it can not be decoded
+func (ssTable *SSTable) readBlock(handle blockHandle) (*block, error) {
+	blockKey := cache.BlockKey{FileId: ssTable.fileId, Offset: handle.offset}
+	if ssTable.blockCache != nil {
+		if cached, ok := ssTable.blockCache.Get(blockKey); ok {
+			return cached.(*block), nil
+		}
+	}
+	block, err := ssTable.readBlockFromStore(handle)
 	if err != nil {
-		return EmptyPersistentSSTableSlice(), EmptyPersistentSSTableSlice(), err
+		return nil, err
+	}
+	if ssTable.blockCache != nil {
+		ssTable.blockCache.Put(blockKey, block, int64(len(block.contents)))
 	}
-	sizeToRead := ActualTotalSize(bytes)
-	contents := make([]byte, sizeToRead)
+	return block, nil
+}
 
-	_, err = ssTable.store.ReadAt(contents, offset)
+// readBlockFromStore reads the block from the file, verifying its checksum if the version of the ssTable carries one
+func (ssTable *SSTable) readBlockFromStore(handle blockHandle) (*block, error) {
+	bytes := make([]byte, handle.size)
+	if _, err := ssTable.store.ReadAt(bytes, handle.offset); err != nil {
+		//a block which ends beyond the end of the file is truncated
+		if errors.Is(err, io.EOF) {
+			return nil, ssTable.corruptionAt(handle.offset, errors.New("block is truncated"))
+		}
+		return nil, &IOError{FileName: ssTable.store.file.Name(), Offset: handle.offset, Err: err}
+	}
+	block, err := newBlock(bytes, ssTable.version)
 	if err != nil {
-		return EmptyPersistentSSTableSlice(), EmptyPersistentSSTableSlice(), err
+		return nil, ssTable.corruptionAt(handle.offset, err)
+	}
+	return block, nil
+}
+
+// scrub reads the footer, the index and every data block of the ssTable from the file, bypassing the block cache,
+// and checks that the bloom filter contains every key. It returns the corruptions found,
+// and an error only if the ssTable can not be read
+func (ssTable *SSTable) scrub() ([]*CorruptionError, error) {
+	if ssTable.isEmpty() {
+		return nil, nil
+	}
+	var corruptions []*CorruptionError
+	asCorruption := func(err error) bool {
+		var corruptionError *CorruptionError
+		if errors.As(err, &corruptionError) {
+			corruptionError.FileId = ssTable.fileId
+			corruptions = append(corruptions, corruptionError)
+			return true
+		}
+		return false
+	}
+	if _, _, err := NewIndexBlock(ssTable.store).Read(ssTable.version); err != nil && !asCorruption(err) {
+		return nil, err
+	}
+	for _, handle := range ssTable.blockHandles {
+		block, err := ssTable.readBlockFromStore(handle)
+		if err != nil {
+			if asCorruption(err) {
+				continue
+			}
+			return nil, err
+		}
+		entries, err := block.entries()
+		if err != nil {
+			corruptions = append(corruptions, ssTable.corruptionAt(handle.offset, err))
+			continue
+		}
+		//a key missing in the bloom filter makes the ssTable skip the key, it is reported once per block
+		for _, entry := range entries {
+			if !ssTable.bloomFilter.Has(entry.key) {
+				corruptions = append(corruptions, &CorruptionError{
+					FileId:   ssTable.fileId,
+					FileName: ssTable.bloomFilter.FileName(),
+					Offset:   handle.offset,
+					Reason:   "bloom filter does not contain key " + entry.key.AsString(),
+				})
+				break
+			}
+		}
 	}
-	key, value := NewPersistentSSTableSliceKeyValuePair(contents)
-	return key, value, nil
+	return corruptions, nil
 }
 
-func (ssTable *SSTable) writeKeyValues() ([]int64, int64, error) {
+// writeDataBlocks writes the key/value pairs in blocks of about targetBlockSizeBytes and returns the handles of the blocks
+// along with the offset where the blocks end
+func (ssTable *SSTable) writeDataBlocks() ([]blockHandle, int64, error) {
 	var offset int64 = 0
-	beginOffsetByKey := make([]int64, len(ssTable.keyValuePairs))
+	var blockHandles []blockHandle
+	builder := newBlockBuilder()
 
+	writeBlock := func() error {
+		compressed, err := compressBlock(builder.finish(), ssTable.compression)
+		if err != nil {
+			return err
+		}
+		bytesWritten, err := ssTable.store.WriteAt(compressed, offset)
+		if err != nil {
+			return err
+		}
+		blockHandles = append(blockHandles, blockHandle{lastKey: builder.lastKey, offset: offset, size: uint32(bytesWritten)})
+		offset = offset + int64(bytesWritten)
+		builder.reset()
+		return nil
+	}
+	//the versions of a key are kept in a single block, so a lookup reads only the block which the sparse index points to
+	isLastVersion := func(index int) bool {
+		return index+1 == len(ssTable.keyValuePairs) ||
+			ssTable.keyComparator.Compare(ssTable.keyValuePairs[index].Key, ssTable.keyValuePairs[index+1].Key) != 0
+	}
 	for index, keyValuePair := range ssTable.keyValuePairs {
-		if bytesWritten, err := ssTable.store.WriteAt(NewPersistentSSTableSlice(keyValuePair).GetPersistentContents(), offset); err != nil {
+		builder.add(keyValuePair)
+		if err := ssTable.bloomFilter.Put(keyValuePair.Key); err != nil {
 			return nil, 0, err
-		} else {
-			//Assignment:SSTable:2:capture the begin-offset of the key to be used in index block
-			fmt.Println(index)
-			offset = offset + int64(bytesWritten)
 		}
-		if err := ssTable.bloomFilter.Put(keyValuePair.Key); err != nil {
+		if builder.isFull() && isLastVersion(index) {
+			if err := writeBlock(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if !builder.isEmpty() {
+		if err := writeBlock(); err != nil {
 			return nil, 0, err
 		}
 	}
-	return beginOffsetByKey, offset, nil
+	return blockHandles, offset, nil
+}
+
+func (ssTable *SSTable) corruptionAt(offset int64, err error) *CorruptionError {
+	return &CorruptionError{FileId: ssTable.fileId, FileName: ssTable.store.file.Name(), Offset: offset, Reason: err.Error()}
 }
 
 func createBloomFilter(fileNamePrefix int, totalKeys int, bloomFilters *filter.BloomFilters) (*filter.BloomFilter, error) {
@@ -117,3 +402,7 @@ func createBloomFilter(fileNamePrefix int, totalKeys int, bloomFilters *filter.B
 	}
 	return bloomFilter, nil
 }
+
+func ssTableFileName(fileId int, level int) string {
+	return fmt.Sprintf("%v_%v%v", fileId, level, ssTableFileExtension)
+}
@@ -0,0 +1,132 @@
+package sst
+
+import (
+	"log"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+)
+
+// SSTableIterator reads one data block at a time, the block is located using the sparse index.
+// It returns the newest version of each key with a sequence less than or equal to the sequence of the iterator,
+// unless it is created to return all the versions
+type SSTableIterator struct {
+	ssTable       *SSTable
+	blockHandles  []blockHandle
+	blockIndex    int
+	entries       []blockEntry
+	index         int
+	err           error
+	sequence      uint64
+	allVersions   bool
+	keyComparator comparator.KeyComparator
+}
+
+func newSSTableIterator(ssTable *SSTable, blockHandles []blockHandle, sequence uint64, keyComparator comparator.KeyComparator) *SSTableIterator {
+	return &SSTableIterator{
+		ssTable:       ssTable,
+		blockHandles:  blockHandles,
+		blockIndex:    len(blockHandles),
+		sequence:      sequence,
+		keyComparator: keyComparator,
+	}
+}
+
+func (ssTableIterator *SSTableIterator) Seek(key model.Slice) {
+	ssTableIterator.readBlock(blockIndexFor(ssTableIterator.blockHandles, key, ssTableIterator.keyComparator))
+	ssTableIterator.index = search(ssTableIterator.entries, key, ssTableIterator.keyComparator)
+	ssTableIterator.skipInvisibleVersions(model.NilSlice(), false)
+}
+
+func (ssTableIterator *SSTableIterator) Next() {
+	if !ssTableIterator.IsValid() {
+		return
+	}
+	key := ssTableIterator.Key()
+	ssTableIterator.advance()
+	ssTableIterator.skipInvisibleVersions(key, true)
+}
+
+func (ssTableIterator *SSTableIterator) IsValid() bool {
+	return ssTableIterator.blockIndex < len(ssTableIterator.blockHandles) && ssTableIterator.index < len(ssTableIterator.entries)
+}
+
+func (ssTableIterator *SSTableIterator) Key() model.Slice {
+	return ssTableIterator.entries[ssTableIterator.index].key
+}
+
+func (ssTableIterator *SSTableIterator) Value() model.Slice {
+	return ssTableIterator.entries[ssTableIterator.index].value
+}
+
+func (ssTableIterator *SSTableIterator) IsDeleted() bool {
+	return ssTableIterator.entries[ssTableIterator.index].kind == kindDelete
+}
+
+// IsValuePointer returns true if the Value is an encoded pointer to the value in the value log, the iterator does not resolve it
+func (ssTableIterator *SSTableIterator) IsValuePointer() bool {
+	return ssTableIterator.entries[ssTableIterator.index].kind == kindValuePointer
+}
+
+func (ssTableIterator *SSTableIterator) Sequence() uint64 {
+	return ssTableIterator.entries[ssTableIterator.index].sequence
+}
+
+// Err returns the error which made the iterator invalid before reaching the end of the ssTable
+func (ssTableIterator *SSTableIterator) Err() error {
+	return ssTableIterator.err
+}
+
+func (ssTableIterator *SSTableIterator) Close() {
+	if ssTableIterator.ssTable == nil {
+		return
+	}
+	if err := ssTableIterator.ssTable.release(); err != nil {
+		log.Default().Println("Error while releasing the ssTable " + err.Error())
+	}
+	ssTableIterator.ssTable = nil
+	ssTableIterator.blockHandles, ssTableIterator.entries = nil, nil
+	ssTableIterator.blockIndex, ssTableIterator.index = 0, 0
+}
+
+func (ssTableIterator *SSTableIterator) advance() {
+	ssTableIterator.index = ssTableIterator.index + 1
+	if ssTableIterator.index == len(ssTableIterator.entries) {
+		ssTableIterator.readBlock(ssTableIterator.blockIndex + 1)
+	}
+}
+
+// skipInvisibleVersions skips the versions newer than the sequence of the iterator and the older versions of the previous key
+func (ssTableIterator *SSTableIterator) skipInvisibleVersions(previousKey model.Slice, hasPreviousKey bool) {
+	if ssTableIterator.allVersions {
+		return
+	}
+	for ssTableIterator.IsValid() {
+		entry := ssTableIterator.entries[ssTableIterator.index]
+		isPreviousKey := hasPreviousKey && ssTableIterator.keyComparator.Compare(entry.key, previousKey) == 0
+		if !isPreviousKey && entry.sequence <= ssTableIterator.sequence {
+			return
+		}
+		ssTableIterator.advance()
+	}
+}
+
+// readBlock positions the iterator at the beginning of the block, an error while reading it makes the iterator invalid
+func (ssTableIterator *SSTableIterator) readBlock(blockIndex int) {
+	ssTableIterator.blockIndex, ssTableIterator.entries, ssTableIterator.index = blockIndex, nil, 0
+	if blockIndex >= len(ssTableIterator.blockHandles) {
+		return
+	}
+	block, err := ssTableIterator.ssTable.readBlock(ssTableIterator.blockHandles[blockIndex])
+	if err != nil {
+		ssTableIterator.err = err
+		ssTableIterator.blockIndex = len(ssTableIterator.blockHandles)
+		return
+	}
+	entries, err := block.entries()
+	if err != nil {
+		ssTableIterator.err = ssTableIterator.ssTable.corruptionAt(ssTableIterator.blockHandles[blockIndex].offset, err)
+		ssTableIterator.blockIndex = len(ssTableIterator.blockHandles)
+		return
+	}
+	ssTableIterator.entries = entries
+}
@@ -0,0 +1,53 @@
+package sst
+
+import (
+	"os"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/memory"
+	"testing"
+)
+
+func TestIteratesOverAllKeysInSSTableInOrder(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	memTable.Delete(model.NewSlice([]byte("PMEM")))
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+
+	iterator, err := ssTable.NewIterator(comparator.StringKeyComparator{})
+	if err != nil {
+		t.Fatalf("Expected no error while creating an ssTable iterator but received %v", err)
+	}
+	iterator.Seek(model.NilSlice())
+
+	expected := []model.KeyValuePair{
+		{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk"))},
+		{Key: model.NewSlice([]byte("PMEM")), Value: model.NilSlice(), Deleted: true},
+		{Key: model.NewSlice([]byte("SDD")), Value: model.NewSlice([]byte("Solid state"))},
+	}
+	for _, keyValuePair := range expected {
+		if !iterator.IsValid() {
+			t.Fatalf("Expected iterator to be valid at key %v, but was not", keyValuePair.Key.AsString())
+		}
+		if iterator.Key().AsString() != keyValuePair.Key.AsString() {
+			t.Fatalf("Expected key to be %v, received %v", keyValuePair.Key.AsString(), iterator.Key().AsString())
+		}
+		if iterator.Value().AsString() != keyValuePair.Value.AsString() {
+			t.Fatalf("Expected value to be %v, received %v", keyValuePair.Value.AsString(), iterator.Value().AsString())
+		}
+		if iterator.IsDeleted() != keyValuePair.Deleted {
+			t.Fatalf("Expected deleted to be %v for key %v, received %v", keyValuePair.Deleted, keyValuePair.Key.AsString(), iterator.IsDeleted())
+		}
+		iterator.Next()
+	}
+	if iterator.IsValid() {
+		t.Fatalf("Expected iterator to be exhausted, but was valid at key %v", iterator.Key().AsString())
+	}
+}
@@ -2,26 +2,59 @@ package sst
 
 import (
 	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"log"
 	"os"
 	"path"
+	"sort"
 	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/cache"
 	"storage-engine-workshop/storage/comparator"
 	"storage-engine-workshop/storage/filter"
+	"storage-engine-workshop/storage/iterator"
+	"storage-engine-workshop/storage/manifest"
 	"storage-engine-workshop/storage/memory"
+	"storage-engine-workshop/storage/snapshot"
+	"storage-engine-workshop/storage/vlog"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-const subDirectoryPermission = 0744
+const (
+	subDirectoryPermission = 0744
+	ssTableFileExtension   = ".sst"
+	maxConcurrentLookups   = 8
+)
 
+// SSTables organises ssTables in levels.
+// Level 0 contains the ssTables flushed from memTables, which may overlap and are searched newest first.
+// Level 1 and above contain ssTables with non-overlapping key ranges, sorted by their smallest key as per the key comparator.
+// Large values are kept in the value log, which the ssTables resolve their value pointers through.
+// Data blocks read by all the ssTables are cached in a shared block cache, if one is given.
+// Blocks of the ssTables written are compressed with the compression codec, ssTables written with other codecs remain readable.
 type SSTables struct {
 	directory    string
 	nextFileId   int
-	tables       []*SSTable
+	levels       [][]*SSTable
 	bloomFilters *filter.BloomFilters
+	manifest     *manifest.Manifest
+	valueLog     *vlog.ValueLog
+	blockCache   *cache.BlockCache
+	compression  CompressionCodec
+	comparator   comparator.KeyComparator
+	compactor    *compactor
 	lock         sync.RWMutex
 }
 
 func NewSSTables(directory string) (*SSTables, error) {
+	return NewSSTablesWith(directory, nil, NoCompression{}, comparator.ByteWiseComparator{})
+}
+
+// NewSSTablesWith returns SSTables which order the keys with the key comparator, it must be the comparator the ssTables were written with
+func NewSSTablesWith(directory string, blockCache *cache.BlockCache, compressionCodec CompressionCodec, keyComparator comparator.KeyComparator) (*SSTables, error) {
 	if len(directory) == 0 {
 		return nil, errors.New("directory can not be empty while creating SSTables")
 	}
@@ -35,11 +68,30 @@ func NewSSTables(directory string) (*SSTables, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SSTables{
+	manifestExists := manifest.Exists(directory)
+	tablesManifest, err := manifest.NewManifest(directory)
+	if err != nil {
+		return nil, err
+	}
+	valueLog, err := vlog.NewValueLog(directory, vlog.DefaultMaxFileSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	ssTables := &SSTables{
 		directory:    subDirectory,
 		bloomFilters: bloomFilters,
+		manifest:     tablesManifest,
+		valueLog:     valueLog,
+		blockCache:   blockCache,
+		compression:  compressionCodec,
+		comparator:   keyComparator,
 		nextFileId:   1,
-	}, nil
+		levels:       make([][]*SSTable, 1),
+	}
+	if err := ssTables.init(manifestExists); err != nil {
+		return nil, err
+	}
+	return ssTables, nil
 }
 
 func (ssTables *SSTables) NewSSTable(memTable *memory.MemTable) (*SSTable, error) {
@@ -50,39 +102,359 @@ func (ssTables *SSTables) NewSSTable(memTable *memory.MemTable) (*SSTable, error
 	if err != nil {
 		return nil, err
 	}
+	ssTables.share(ssTable)
 	ssTables.nextFileId = ssTables.nextFileId + 1
 	return ssTable, nil
 }
 
-func (ssTables *SSTables) AllowSearchIn(ssTable *SSTable) {
+// AllowSearchIn records the ssTable as live in the manifest before making it searchable.
+// The directory entries of the ssTable and its bloom filter are made durable before the manifest refers to them
+func (ssTables *SSTables) AllowSearchIn(ssTable *SSTable) error {
+	ssTables.lock.RLock()
+	edit := manifest.NewEdit().AddTable(ssTable.fileId, ssTable.level, int(ssTable.version)).WithNextFileId(ssTables.nextFileId)
+	ssTables.lock.RUnlock()
+
+	if err := ssTables.syncDirectories(); err != nil {
+		return err
+	}
+	if err := ssTables.manifest.Apply(edit); err != nil {
+		return err
+	}
+	ssTables.lock.Lock()
+	ssTables.levels[0] = append(ssTables.levels[0], ssTable)
+	ssTables.lock.Unlock()
+
+	if ssTables.compactor != nil {
+		ssTables.compactor.signal()
+	}
+	return nil
+}
+
+// SetPersisted records that the WAL contents before the offset, and the writes up to the lastSequence, are persisted in ssTables
+func (ssTables *SSTables) SetPersisted(walOffset int64, lastSequence uint64) error {
+	return ssTables.manifest.Apply(manifest.NewEdit().WithPersistedWALOffset(walOffset).WithLastSequence(lastSequence))
+}
+
+func (ssTables *SSTables) PersistedWALOffset() int64 {
+	return ssTables.manifest.PersistedWALOffset()
+}
+
+// PersistedSequence returns the sequence of the newest write persisted in ssTables
+func (ssTables *SSTables) PersistedSequence() uint64 {
+	return ssTables.manifest.LastSequence()
+}
+
+// StartCompaction starts compacting ssTables in background, everytime a new ssTable is allowed to be searched.
+// Compaction keeps the versions of keys which the live snapshots see
+func (ssTables *SSTables) StartCompaction(options CompactionOptions, keyComparator comparator.KeyComparator, snapshots *snapshot.Snapshots) {
+	ssTables.compactor = newCompactor(ssTables, options, keyComparator, snapshots)
+	ssTables.compactor.start()
+	ssTables.compactor.signal()
+}
+
+// Close stops the compaction, closes the ssTables, the bloom filters, the manifest and the value log, and returns the first error
+func (ssTables *SSTables) Close() error {
+	if ssTables.compactor != nil {
+		ssTables.compactor.stop()
+	}
 	ssTables.lock.Lock()
 	defer ssTables.lock.Unlock()
 
-	ssTables.tables = append(ssTables.tables, ssTable)
+	var err error
+	keepFirst := func(closeErr error) {
+		if err == nil {
+			err = closeErr
+		}
+	}
+	for _, level := range ssTables.levels {
+		for _, ssTable := range level {
+			keepFirst(ssTable.Close())
+		}
+	}
+	keepFirst(ssTables.bloomFilters.Close())
+	keepFirst(ssTables.manifest.Close())
+	keepFirst(ssTables.valueLog.Close())
+	return err
+}
+
+func (ssTables *SSTables) ValueLog() *vlog.ValueLog {
+	return ssTables.valueLog
 }
 
 func (ssTables *SSTables) Get(key model.Slice, keyComparator comparator.KeyComparator) model.GetResult {
 	ssTables.lock.RLock()
 	defer ssTables.lock.RUnlock()
 
-	for index := len(ssTables.tables) - 1; index >= 0; index-- {
-		table := ssTables.tables[index]
+	return ssTables.get(key, model.LatestSequence, keyComparator, true)
+}
+
+// GetUnresolved returns the newest version of the key with a sequence less than or equal to the sequence.
+// It returns the encoded value pointer instead of the value for a key whose value is in the value log
+func (ssTables *SSTables) GetUnresolved(key model.Slice, sequence uint64, keyComparator comparator.KeyComparator) model.GetResult {
+	ssTables.lock.RLock()
+	defer ssTables.lock.RUnlock()
+
+	return ssTables.get(key, sequence, keyComparator, false)
+}
+
+func (ssTables *SSTables) MultiGet(keys []model.Slice, keyComparator comparator.KeyComparator) model.MultiGetResult {
+	return ssTables.MultiGetAt(keys, model.LatestSequence, keyComparator)
+}
+
+// MultiGetAt returns the newest versions of the keys with a sequence less than or equal to the sequence,
+// positionally aligned with the keys. The keys are looked up concurrently, at most maxConcurrentLookups at a time
+func (ssTables *SSTables) MultiGetAt(keys []model.Slice, sequence uint64, keyComparator comparator.KeyComparator) model.MultiGetResult {
+	ssTables.lock.RLock()
+	defer ssTables.lock.RUnlock()
+
+	values := make([]model.GetResult, len(keys))
+	lookups := make(chan struct{}, maxConcurrentLookups)
+	var wg sync.WaitGroup
+	wg.Add(len(keys))
+
+	for index, key := range keys {
+		lookups <- struct{}{}
+		go func(index int, key model.Slice) {
+			defer wg.Done()
+			values[index] = ssTables.get(key, sequence, keyComparator, true)
+			<-lookups
+		}(index, key)
+	}
+	wg.Wait()
+	return model.MultiGetResult{Values: values}
+}
+
+// NewIterators returns one iterator per searchable ssTable, newest first,
+// over the newest version of each key with a sequence less than or equal to the sequence
+func (ssTables *SSTables) NewIterators(sequence uint64, keyComparator comparator.KeyComparator) ([]iterator.Iterator, error) {
+	ssTables.lock.RLock()
+	defer ssTables.lock.RUnlock()
+
+	var iterators []iterator.Iterator
+	for _, table := range ssTables.newestFirst() {
+		iterators = append(iterators, table.newIteratorAt(sequence, keyComparator))
+	}
+	return iterators, nil
+}
+
+// Scrub reads every searchable ssTable and its bloom filter from disk, bypassing the block cache, and returns the corruptions found.
+// It returns an error if an ssTable can not be read, the corruptions of the other ssTables are not returned then
+func (ssTables *SSTables) Scrub() ([]*CorruptionError, error) {
+	ssTables.lock.RLock()
+	tables := ssTables.newestFirst()
+	for _, table := range tables {
+		table.acquire()
+	}
+	ssTables.lock.RUnlock()
+
+	//the ssTables are released even if scrubbing fails, a compaction may have removed them while they were scrubbed
+	releaseAll := func() {
+		for _, table := range tables {
+			if err := table.release(); err != nil {
+				log.Default().Println("Error while releasing the ssTable " + err.Error())
+			}
+		}
+	}
+	defer releaseAll()
+
+	var corruptions []*CorruptionError
+	for _, table := range tables {
+		tableCorruptions, err := table.scrub()
+		if err != nil {
+			return nil, err
+		}
+		corruptions = append(corruptions, tableCorruptions...)
+	}
+	return corruptions, nil
+}
+
+func (ssTables *SSTables) get(key model.Slice, sequence uint64, keyComparator comparator.KeyComparator, resolveValuePointer bool) model.GetResult {
+	getFrom := func(table *SSTable) (model.GetResult, bool) {
 		if table.bloomFilter.Has(key) {
-			if getResult := table.Get(key, keyComparator); getResult.Exists {
+			getResult := table.getUnresolved(key, sequence, keyComparator)
+			if resolveValuePointer {
+				getResult = table.resolve(getResult)
+			}
+			if getResult.Exists || getResult.Deleted || getResult.Err != nil {
+				return getResult, true
+			}
+		}
+		return model.GetResult{}, false
+	}
+	level0 := ssTables.levels[0]
+	for index := len(level0) - 1; index >= 0; index-- {
+		if getResult, ok := getFrom(level0[index]); ok {
+			return getResult
+		}
+	}
+	for level := 1; level < len(ssTables.levels); level++ {
+		tables := ssTables.levels[level]
+		index := sort.Search(len(tables), func(index int) bool {
+			return keyComparator.Compare(tables[index].largestKey, key) >= 0
+		})
+		if index < len(tables) && tables[index].mayContain(key, keyComparator) {
+			if getResult, ok := getFrom(tables[index]); ok {
 				return getResult
 			}
 		}
 	}
-	return model.GetResult{Exists: false}
+	return model.GetResult{Key: key, Exists: false}
 }
 
-func (ssTables *SSTables) MultiGet(keys []model.Slice, keyComparator comparator.KeyComparator) model.MultiGetResult {
-	ssTables.lock.RLock()
-	defer ssTables.lock.RUnlock()
+// newestFirst returns all the searchable ssTables, level 0 newest first followed by the higher levels
+func (ssTables *SSTables) newestFirst() []*SSTable {
+	var tables []*SSTable
+	level0 := ssTables.levels[0]
+	for index := len(level0) - 1; index >= 0; index-- {
+		tables = append(tables, level0[index])
+	}
+	for level := 1; level < len(ssTables.levels); level++ {
+		tables = append(tables, ssTables.levels[level]...)
+	}
+	return tables
+}
 
-	response := model.MultiGetResult{}
-	for _, key := range keys {
-		response.Add(ssTables.Get(key, keyComparator))
+// init reloads the ssTables which are live as per the manifest and removes the others, which are left over by
+// flushes or compactions that could not complete. A directory without a manifest has all its ssTables reloaded.
+func (ssTables *SSTables) init(manifestExists bool) error {
+	type ssTableFile struct {
+		name   string
+		fileId int
+		level  int
+		size   int64
+	}
+	sortedFiles := func() ([]ssTableFile, error) {
+		files, err := ioutil.ReadDir(ssTables.directory)
+		if err != nil {
+			return nil, err
+		}
+		var ssTableFiles []ssTableFile
+		for _, file := range files {
+			if path.Ext(file.Name()) != ssTableFileExtension {
+				continue
+			}
+			if fileId, level, err := parseSSTableFileName(file); err == nil {
+				ssTableFiles = append(ssTableFiles, ssTableFile{name: file.Name(), fileId: fileId, level: level, size: file.Size()})
+			}
+		}
+		sort.Slice(ssTableFiles, func(i, j int) bool {
+			return ssTableFiles[i].fileId < ssTableFiles[j].fileId
+		})
+		return ssTableFiles, nil
+	}
+	//the version of an ssTable reloaded without a manifest is detected from its footer
+	liveTableByFileId := func(files []ssTableFile) map[int]manifest.TableEntry {
+		tableByFileId := make(map[int]manifest.TableEntry)
+		//an ssTable file without contents was created but never written, it is an orphan
+		if !manifestExists {
+			for _, file := range files {
+				if file.size > 0 {
+					tableByFileId[file.fileId] = manifest.TableEntry{FileId: file.fileId, Level: file.level}
+				}
+			}
+			return tableByFileId
+		}
+		for _, table := range ssTables.manifest.LiveTables() {
+			tableByFileId[table.FileId] = table
+		}
+		return tableByFileId
+	}
+	removeOrphan := func(file ssTableFile) error {
+		if err := os.Remove(path.Join(ssTables.directory, file.name)); err != nil {
+			return err
+		}
+		if bloomFilter, ok := ssTables.bloomFilters.BloomFilterWith(strconv.Itoa(file.fileId)); ok {
+			return ssTables.bloomFilters.Delete(bloomFilter)
+		}
+		return nil
+	}
+	reOpenSSTables := func() error {
+		files, err := sortedFiles()
+		if err != nil {
+			return err
+		}
+		tableByFileId := liveTableByFileId(files)
+		edit := manifest.NewEdit()
+		for _, file := range files {
+			ssTables.nextFileId = file.fileId + 1
+			table, live := tableByFileId[file.fileId]
+			if !live {
+				if err := removeOrphan(file); err != nil {
+					return err
+				}
+				continue
+			}
+			ssTable, err := NewSSTableFromFileOfVersion(ssTables.bloomFilters, ssTables.directory, file.name, file.fileId, table.Level, uint16(table.Version))
+			if err != nil {
+				return err
+			}
+			if ssTable.isEmpty() {
+				_ = ssTable.Close()
+				return errors.New(fmt.Sprintf("ssTable with file id %v is live as per the manifest but its file is empty", file.fileId))
+			}
+			ssTables.share(ssTable)
+			ssTables.addToLevel(ssTable)
+			edit.AddTable(file.fileId, table.Level, int(ssTable.version))
+			delete(tableByFileId, file.fileId)
+		}
+		for fileId := range tableByFileId {
+			return errors.New(fmt.Sprintf("ssTable with file id %v is live as per the manifest but its file is missing", fileId))
+		}
+		if ssTables.manifest.NextFileId() > ssTables.nextFileId {
+			ssTables.nextFileId = ssTables.manifest.NextFileId()
+		}
+		if !manifestExists {
+			return ssTables.manifest.Apply(edit.WithNextFileId(ssTables.nextFileId))
+		}
+		return nil
+	}
+	return reOpenSSTables()
+}
+
+// syncDirectories makes the entries of the ssTable and bloom filter files created in their directories durable
+func (ssTables *SSTables) syncDirectories() error {
+	if err := syncDirectory(ssTables.directory); err != nil {
+		return err
+	}
+	return ssTables.bloomFilters.SyncDirectory()
+}
+
+// share gives the ssTable access to the value log, the block cache, the compression codec and the key comparator which are shared by all the ssTables
+func (ssTables *SSTables) share(ssTable *SSTable) {
+	ssTable.valueLog, ssTable.blockCache, ssTable.compression = ssTables.valueLog, ssTables.blockCache, ssTables.compression
+	ssTable.keyComparator = ssTables.comparator
+}
+
+func (ssTables *SSTables) addToLevel(ssTable *SSTable) {
+	for len(ssTables.levels) <= ssTable.level {
+		ssTables.levels = append(ssTables.levels, []*SSTable{})
+	}
+	ssTables.levels[ssTable.level] = append(ssTables.levels[ssTable.level], ssTable)
+	if ssTable.level > 0 {
+		ssTables.sortLevel(ssTable.level, ssTables.comparator)
+	}
+}
+
+func (ssTables *SSTables) sortLevel(level int, keyComparator comparator.KeyComparator) {
+	tables := ssTables.levels[level]
+	sort.Slice(tables, func(i, j int) bool {
+		return keyComparator.Compare(tables[i].smallestKey, tables[j].smallestKey) < 0
+	})
+}
+
+// parseSSTableFileName parses <fileId>_<level>.sst, ssTables named <fileId>.sst belong to level 0
+func parseSSTableFileName(file fs.FileInfo) (int, int, error) {
+	nameParts := strings.Split(strings.TrimSuffix(file.Name(), path.Ext(file.Name())), "_")
+	fileId, err := strconv.Atoi(nameParts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(nameParts) == 1 {
+		return fileId, 0, nil
+	}
+	level, err := strconv.Atoi(nameParts[1])
+	if err != nil {
+		return 0, 0, err
 	}
-	return response
+	return fileId, level, nil
 }
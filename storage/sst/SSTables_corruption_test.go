@@ -0,0 +1,236 @@
+package sst
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/comparator"
+	"storage-engine-workshop/storage/iterator"
+	"storage-engine-workshop/storage/memory"
+	"storage-engine-workshop/storage/snapshot"
+	"testing"
+)
+
+func searchableSSTableSpanningMultipleBlocks(directory string) (*SSTables, *SSTable) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	for count := 1; count <= 100; count++ {
+		memTable.Put(model.NewSlice([]byte(fmt.Sprintf("Key-%03d", count))), model.NewSlice([]byte(fmt.Sprintf("Value-%0100d", count))))
+	}
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	if err := ssTable.Write(); err != nil {
+		log.Fatal(err)
+	}
+	if err := ssTables.AllowSearchIn(ssTable); err != nil {
+		log.Fatal(err)
+	}
+	return ssTables, ssTable
+}
+
+func TestScrubsSSTablesWithoutCorruptions(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := searchableSSTableSpanningMultipleBlocks(directory)
+
+	corruptions, err := ssTables.Scrub()
+	if err != nil {
+		t.Fatalf("Expected no error while scrubbing ssTables, received %v", err)
+	}
+	if len(corruptions) != 0 {
+		t.Fatalf("Expected no corruptions, received %v", corruptions)
+	}
+}
+
+func TestReturnsACorruptionErrorWhileGettingFromABlockFailingItsChecksum(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, ssTable := searchableSSTableSpanningMultipleBlocks(directory)
+	flipByteAt(ssTable.store.file.Name(), 10)
+
+	getResult := ssTables.Get(model.NewSlice([]byte("Key-001")), comparator.StringKeyComparator{})
+	var corruptionError *CorruptionError
+	if !errors.As(getResult.Err, &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while getting from a block failing its checksum, received %v", getResult.Err)
+	}
+	if corruptionError.FileId != ssTable.fileId || corruptionError.Offset != 0 {
+		t.Fatalf("Expected the corruption in file id %v at offset 0, received file id %v at offset %v", ssTable.fileId, corruptionError.FileId, corruptionError.Offset)
+	}
+}
+
+func TestScrubReportsACorruptBlockWithTheFileIdAndOffset(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, ssTable := searchableSSTableSpanningMultipleBlocks(directory)
+	if len(ssTable.blockHandles) <= 1 {
+		t.Fatalf("Expected the ssTable to have multiple blocks, received %v", len(ssTable.blockHandles))
+	}
+	corruptBlock := ssTable.blockHandles[1]
+	flipByteAt(ssTable.store.file.Name(), corruptBlock.offset+10)
+
+	corruptions, err := ssTables.Scrub()
+	if err != nil {
+		t.Fatalf("Expected no error while scrubbing ssTables, received %v", err)
+	}
+	if len(corruptions) != 1 {
+		t.Fatalf("Expected %v corruption, received %v", 1, corruptions)
+	}
+	if corruptions[0].FileId != ssTable.fileId || corruptions[0].Offset != corruptBlock.offset {
+		t.Fatalf("Expected the corruption in file id %v at offset %v, received file id %v at offset %v", ssTable.fileId, corruptBlock.offset, corruptions[0].FileId, corruptions[0].Offset)
+	}
+}
+
+func TestScrubReportsACorruptFooter(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, ssTable := searchableSSTableSpanningMultipleBlocks(directory)
+	size, _ := ssTable.store.Size()
+	flipByteAt(ssTable.store.file.Name(), size-footerSize+8)
+
+	corruptions, err := ssTables.Scrub()
+	if err != nil {
+		t.Fatalf("Expected no error while scrubbing ssTables, received %v", err)
+	}
+	if len(corruptions) != 1 || corruptions[0].Offset != size-footerSize {
+		t.Fatalf("Expected %v corruption at offset %v, received %v", 1, size-footerSize, corruptions)
+	}
+}
+
+func TestScrubReportsAFooterWithACorruptMagic(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, ssTable := searchableSSTableSpanningMultipleBlocks(directory)
+	size, _ := ssTable.store.Size()
+	flipByteAt(ssTable.store.file.Name(), size-footerSize+12)
+
+	corruptions, err := ssTables.Scrub()
+	if err != nil {
+		t.Fatalf("Expected no error while scrubbing ssTables, received %v", err)
+	}
+	if len(corruptions) != 1 || corruptions[0].FileId != ssTable.fileId || corruptions[0].Offset != size-footerSize {
+		t.Fatalf("Expected %v corruption in file id %v at offset %v, received %v", 1, ssTable.fileId, size-footerSize, corruptions)
+	}
+}
+
+func TestReturnsACorruptionErrorWhileReloadingAnSSTableWithACorruptMagicSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	_, ssTable := searchableSSTableSpanningMultipleBlocks(directory)
+	size, _ := ssTable.store.Size()
+	flipByteAt(ssTable.store.file.Name(), size-footerSize+12)
+
+	_, err := NewSSTables(directory)
+	var corruptionError *CorruptionError
+	if !errors.As(err, &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while reloading an ssTable with a corrupt magic, received %v", err)
+	}
+	if corruptionError.FileId != ssTable.fileId || corruptionError.Offset != size-footerSize {
+		t.Fatalf("Expected the corruption in file id %v at offset %v, received file id %v at offset %v", ssTable.fileId, size-footerSize, corruptionError.FileId, corruptionError.Offset)
+	}
+}
+
+func TestScrubReportsABloomFilterMissingKeys(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, ssTable := searchableSSTableSpanningMultipleBlocks(directory)
+	stat, _ := os.Stat(ssTable.bloomFilter.FileName())
+	zeroFile(ssTable.bloomFilter.FileName(), stat.Size())
+
+	corruptions, err := ssTables.Scrub()
+	if err != nil {
+		t.Fatalf("Expected no error while scrubbing ssTables, received %v", err)
+	}
+	if len(corruptions) != len(ssTable.blockHandles) {
+		t.Fatalf("Expected a corruption per block, %v, received %v", len(ssTable.blockHandles), corruptions)
+	}
+	if corruptions[0].FileId != ssTable.fileId || corruptions[0].FileName != ssTable.bloomFilter.FileName() {
+		t.Fatalf("Expected the corruption in bloom filter %v of file id %v, received %v", ssTable.bloomFilter.FileName(), ssTable.fileId, corruptions[0])
+	}
+}
+
+func TestStopsMergingIteratorsOnceAnSSTableIteratorFails(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, corruptSSTable := searchableSSTableSpanningMultipleBlocks(directory)
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("Key-050")), model.NewSlice([]byte("Newer value")))
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	_ = ssTables.AllowSearchIn(ssTable)
+
+	flipByteAt(corruptSSTable.store.file.Name(), corruptSSTable.blockHandles[1].offset+10)
+
+	iterators, _ := ssTables.NewIterators(model.LatestSequence, comparator.StringKeyComparator{})
+	mergedIterator := iterator.NewMergedIterator(iterators, comparator.StringKeyComparator{})
+	defer mergedIterator.Close()
+
+	count := 0
+	for mergedIterator.Seek(model.NilSlice()); mergedIterator.IsValid(); mergedIterator.Next() {
+		count = count + 1
+	}
+	var corruptionError *CorruptionError
+	if !errors.As(mergedIterator.Err(), &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while iterating over a corrupt ssTable, received %v", mergedIterator.Err())
+	}
+	if count >= 100 {
+		t.Fatalf("Expected the iterator to stop at the corrupt block, received %v keys", count)
+	}
+}
+
+func TestRemovesTheWrittenOutputsOfACompactionFailingOnACorruptBlock(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, corruptSSTable := searchableSSTableSpanningMultipleBlocks(directory)
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("Key-001")), model.NewSlice([]byte("Newer value")))
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	_ = ssTables.AllowSearchIn(ssTable)
+
+	lastBlock := corruptSSTable.blockHandles[len(corruptSSTable.blockHandles)-1]
+	flipByteAt(corruptSSTable.store.file.Name(), lastBlock.offset+10)
+
+	options := compactionOptions()
+	options.TargetFileSizeBytes = 300
+	if _, err := newCompactor(ssTables, options, comparator.StringKeyComparator{}, snapshot.NewSnapshots()).compact(); err == nil {
+		t.Fatalf("Expected an error while compacting a corrupt ssTable, received none")
+	}
+	files, _ := ioutil.ReadDir(ssTables.directory)
+	if len(files) != 2 {
+		t.Fatalf("Expected only the %v input ssTables to be left, received %v files", 2, len(files))
+	}
+}
+
+func flipByteAt(filePath string, offset int64) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	bytes := make([]byte, 1)
+	_, _ = file.ReadAt(bytes, offset)
+	bytes[0] = bytes[0] ^ 0xff
+	_, _ = file.WriteAt(bytes, offset)
+}
+
+func zeroFile(filePath string, size int64) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	_, _ = file.WriteAt(make([]byte, size), 0)
+}
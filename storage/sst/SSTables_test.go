@@ -1,10 +1,14 @@
 package sst
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/cache"
 	"storage-engine-workshop/storage/comparator"
 	"storage-engine-workshop/storage/memory"
 	"testing"
@@ -170,3 +174,502 @@ func TestMultiGetsFromSSTablesBasedOnBloomFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestReloadsSSTablesSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	memTableA := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTableA.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	ssTableA, _ := ssTables.NewSSTable(memTableA)
+	_ = ssTableA.Write()
+	ssTables.AllowSearchIn(ssTableA)
+
+	memTableB := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTableB.Put(model.NewSlice([]byte("PMEM")), model.NewSlice([]byte("Persistent memory")))
+
+	ssTableB, _ := ssTables.NewSSTable(memTableB)
+	_ = ssTableB.Write()
+	ssTables.AllowSearchIn(ssTableB)
+
+	ssTablesAfterRestart, err := NewSSTables(directory)
+	if err != nil {
+		t.Fatalf("Expected no error while reloading SSTables but received %v", err)
+	}
+	if getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", getResult.Value.AsString())
+	}
+	if getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte("PMEM")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Persistent memory" {
+		t.Fatalf("Expected %v, received %v", "Persistent memory", getResult.Value.AsString())
+	}
+}
+
+func TestContinuesFileIdAfterTheHighestExistingSSTableSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	for count := 1; count <= 3; count++ {
+		ssTable, _ := ssTables.NewSSTable(memTable)
+		_ = ssTable.Write()
+		ssTables.AllowSearchIn(ssTable)
+	}
+
+	ssTablesAfterRestart, _ := NewSSTables(directory)
+	if ssTablesAfterRestart.nextFileId != 4 {
+		t.Fatalf("Expected next file id to be %v, received %v", 4, ssTablesAfterRestart.nextFileId)
+	}
+	if len(ssTablesAfterRestart.levels[0]) != 3 {
+		t.Fatalf("Expected %v ssTables to be reloaded, received %v", 3, len(ssTablesAfterRestart.levels[0]))
+	}
+}
+
+func TestSearchesNewestReloadedSSTableFirstSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	for _, value := range []string{"Hard disk", "Hard disk drive"} {
+		memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte(value)))
+
+		ssTable, _ := ssTables.NewSSTable(memTable)
+		_ = ssTable.Write()
+		ssTables.AllowSearchIn(ssTable)
+	}
+
+	ssTablesAfterRestart, _ := NewSSTables(directory)
+	if getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Hard disk drive" {
+		t.Fatalf("Expected %v, received %v", "Hard disk drive", getResult.Value.AsString())
+	}
+}
+
+func TestRemovesAnEmptySSTableWithoutAManifestSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	_ = ssTables.AllowSearchIn(ssTable)
+
+	emptySSTable, _ := ssTables.NewSSTable(memTable)
+	_ = os.Remove(path.Join(directory, "MANIFEST"))
+
+	ssTablesAfterRestart, err := NewSSTables(directory)
+	if err != nil {
+		t.Fatalf("Expected no error while reopening ssTables with an empty ssTable and no manifest, received %v", err)
+	}
+	if _, err := os.Stat(emptySSTable.store.file.Name()); !os.IsNotExist(err) {
+		t.Fatalf("Expected the empty ssTable to be removed, but was present")
+	}
+	if getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", getResult.Value.AsString())
+	}
+}
+
+func TestTombstoneInNewerSSTableShadowsOlderSSTable(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	memTableA := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTableA.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	ssTableA, _ := ssTables.NewSSTable(memTableA)
+	_ = ssTableA.Write()
+	ssTables.AllowSearchIn(ssTableA)
+
+	memTableB := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTableB.Delete(model.NewSlice([]byte("HDD")))
+
+	ssTableB, _ := ssTables.NewSSTable(memTableB)
+	_ = ssTableB.Write()
+	ssTables.AllowSearchIn(ssTableB)
+
+	getResult := ssTables.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{})
+	if getResult.Exists {
+		t.Fatalf("Expected key %v to be deleted, but was present with value %v", "HDD", getResult.Value.AsString())
+	}
+}
+
+func TestRemovesSSTablesNotRecordedInTheManifestSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	ssTables, _ := NewSSTables(directory)
+	defer os.RemoveAll(directory)
+
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	liveSSTable, _ := ssTables.NewSSTable(memTable)
+	_ = liveSSTable.Write()
+	_ = ssTables.AllowSearchIn(liveSSTable)
+
+	orphanMemTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	orphanMemTable.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state")))
+	orphanSSTable, _ := ssTables.NewSSTable(orphanMemTable)
+	_ = orphanSSTable.Write()
+
+	ssTablesAfterRestart, _ := NewSSTables(directory)
+	if _, err := os.Stat(orphanSSTable.store.file.Name()); !os.IsNotExist(err) {
+		t.Fatalf("Expected ssTable not recorded in the manifest to be removed, but was present")
+	}
+	if getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte("SDD")), comparator.StringKeyComparator{}); getResult.Exists {
+		t.Fatalf("Expected key %v to be missing, but was present", "SDD")
+	}
+	if getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", getResult.Value.AsString())
+	}
+	if ssTablesAfterRestart.nextFileId != 3 {
+		t.Fatalf("Expected next file id to be %v, received %v", 3, ssTablesAfterRestart.nextFileId)
+	}
+}
+
+func TestGetsFromSSTableSpanningMultipleBlocks(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	for count := 1; count <= 500; count++ {
+		memTable.Put(model.NewSlice([]byte(fmt.Sprintf("Key-%03d", count))), model.NewSlice([]byte(fmt.Sprintf("Value-%0100d", count))))
+	}
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+
+	if len(ssTable.blockHandles) <= 1 {
+		t.Fatalf("Expected the ssTable to have multiple blocks, received %v", len(ssTable.blockHandles))
+	}
+	for count := 1; count <= 500; count++ {
+		getResult := ssTable.Get(model.NewSlice([]byte(fmt.Sprintf("Key-%03d", count))), comparator.StringKeyComparator{})
+		if getResult.Value.AsString() != fmt.Sprintf("Value-%0100d", count) {
+			t.Fatalf("Expected %v, received %v", fmt.Sprintf("Value-%0100d", count), getResult.Value.AsString())
+		}
+	}
+	for _, key := range []string{"Key-000", "Key-2500", "Key-501"} {
+		if getResult := ssTable.Get(model.NewSlice([]byte(key)), comparator.StringKeyComparator{}); getResult.Exists {
+			t.Fatalf("Expected key %v to be missing, but was present", key)
+		}
+	}
+
+	iterator, _ := ssTable.NewIterator(comparator.StringKeyComparator{})
+	defer iterator.Close()
+
+	count := 250
+	for iterator.Seek(model.NewSlice([]byte("Key-2495"))); iterator.IsValid(); iterator.Next() {
+		if iterator.Key().AsString() != fmt.Sprintf("Key-%03d", count) {
+			t.Fatalf("Expected key %v, received %v", fmt.Sprintf("Key-%03d", count), iterator.Key().AsString())
+		}
+		count = count + 1
+	}
+	if count != 501 {
+		t.Fatalf("Expected the iterator to end after key %v, received %v", "Key-500", fmt.Sprintf("Key-%03d", count-1))
+	}
+}
+
+func TestPrefixCompressesKeysSharingALongPrefix(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	keySizes := 0
+	for count := 1; count <= 300; count++ {
+		key := fmt.Sprintf("tenant/storage-engine-workshop/object/%04d", count)
+		keySizes = keySizes + len(key)
+		memTable.Put(model.NewSlice([]byte(key)), model.NewSlice([]byte(fmt.Sprintf("%v", count))))
+	}
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	ssTables.AllowSearchIn(ssTable)
+
+	if ssTable.size >= int64(keySizes) {
+		t.Fatalf("Expected the ssTable to be smaller than the total size %v of its keys, received %v", keySizes, ssTable.size)
+	}
+
+	reopened, _ := NewSSTables(directory)
+	for count := 1; count <= 300; count++ {
+		key := fmt.Sprintf("tenant/storage-engine-workshop/object/%04d", count)
+		getResult := reopened.Get(model.NewSlice([]byte(key)), comparator.StringKeyComparator{})
+		if getResult.Value.AsString() != fmt.Sprintf("%v", count) {
+			t.Fatalf("Expected %v, received %v", fmt.Sprintf("%v", count), getResult.Value.AsString())
+		}
+	}
+	for _, key := range []string{"tenant/storage-engine-workshop/object/0000", "tenant/storage-engine-workshop/object/00171", "tenant/storage-engine-workshop/object/0301"} {
+		if getResult := reopened.Get(model.NewSlice([]byte(key)), comparator.StringKeyComparator{}); getResult.Exists {
+			t.Fatalf("Expected key %v to be missing, but was present", key)
+		}
+	}
+}
+
+func TestReadsALegacySSTableWithAnIndexEntryPerKey(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	keyValuePairs := []model.KeyValuePair{
+		{Key: model.NewSlice([]byte("HDD")), Value: model.NewSlice([]byte("Hard disk"))},
+		{Key: model.NewSlice([]byte("PMEM")), Value: model.NewSlice([]byte("Persistent memory"))},
+		{Key: model.NewSlice([]byte("SDD")), Value: model.NewSlice([]byte("Solid state drive"))},
+	}
+	var contents, index []byte
+	for _, keyValuePair := range keyValuePairs {
+		entry := make([]byte, int(reservedKeySize)+int(ReservedOffsetSize))
+		bigEndian.PutUint32(entry, uint32(keyValuePair.Key.Size()))
+		bigEndian.PutUint64(entry[reservedKeySize:], uint64(len(contents)))
+		index = append(index, append(entry, keyValuePair.Key.GetRawContent()...)...)
+		contents = append(contents, NewPersistentSSTableSlice(keyValuePair).GetPersistentContents()...)
+	}
+	indexBlockBeginOffset := make([]byte, ReservedOffsetSize)
+	bigEndian.PutUint64(indexBlockBeginOffset, uint64(len(contents)))
+	contents = append(append(contents, index...), indexBlockBeginOffset...)
+
+	_ = ioutil.WriteFile(path.Join(ssTables.directory, "1.sst"), contents, 0644)
+	bloomFilter, _ := createBloomFilter(1, len(keyValuePairs), ssTables.bloomFilters)
+	for _, keyValuePair := range keyValuePairs {
+		_ = bloomFilter.Put(keyValuePair.Key)
+	}
+
+	ssTable, err := NewSSTableFromFile(ssTables.bloomFilters, ssTables.directory, "1.sst", 1, 0)
+	if err != nil {
+		t.Fatalf("Expected no error while reading a legacy ssTable, received %v", err)
+	}
+	if getResult := ssTable.Get(model.NewSlice([]byte("SDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Solid state drive" {
+		t.Fatalf("Expected %v, received %v", "Solid state drive", getResult.Value.AsString())
+	}
+	if getResult := ssTable.Get(model.NewSlice([]byte("PMEM")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Persistent memory" {
+		t.Fatalf("Expected %v, received %v", "Persistent memory", getResult.Value.AsString())
+	}
+	if ssTable.smallestKey.AsString() != "HDD" || ssTable.largestKey.AsString() != "SDD" {
+		t.Fatalf("Expected key range %v..%v, received %v..%v", "HDD", "SDD", ssTable.smallestKey.AsString(), ssTable.largestKey.AsString())
+	}
+}
+
+func TestFailsToReadAnSSTableWithAnUnsupportedVersion(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+
+	version := make([]byte, 2)
+	bigEndian.PutUint16(version, currentVersion+1)
+	_, _ = ssTable.store.WriteAt(version, ssTable.size-2)
+
+	if _, err := NewSSTableFromFile(ssTables.bloomFilters, ssTables.directory, ssTableFileName(1, 0), 1, 0); err == nil {
+		t.Fatalf("Expected an error while reading an ssTable with an unsupported version")
+	}
+}
+
+func TestGetsFromSSTableUsingTheBlockCache(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	memTable.Put(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	blockCache := cache.NewBlockCache(1024 * 1024)
+	ssTables, _ := NewSSTablesWith(directory, blockCache, NoCompression{}, comparator.StringKeyComparator{})
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	_ = ssTables.AllowSearchIn(ssTable)
+
+	for count := 1; count <= 3; count++ {
+		if getResult := ssTables.Get(model.NewSlice([]byte("SDD")), comparator.StringKeyComparator{}); getResult.Value.AsString() != "Solid state drive" {
+			t.Fatalf("Expected %v, received %v", "Solid state drive", getResult.Value.AsString())
+		}
+	}
+	if stats := blockCache.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatalf("Expected 2 hits and 1 miss, received %v hits and %v misses", stats.Hits, stats.Misses)
+	}
+}
+
+func TestReadsSSTablesWrittenWithDifferentCompressionCodecs(t *testing.T) {
+	newMemTable := func(keyPrefix string) *memory.MemTable {
+		memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+		for count := 1; count <= 200; count++ {
+			memTable.Put(
+				model.NewSlice([]byte(fmt.Sprintf("%v-%03d", keyPrefix, count))),
+				model.NewSlice([]byte(fmt.Sprintf(`{"disk": "%v", "capacity": "%vGB", "type": "solid state drive"}`, keyPrefix, count))),
+			)
+		}
+		return memTable
+	}
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	uncompressedSSTables, _ := NewSSTablesWith(directory, nil, NoCompression{}, comparator.StringKeyComparator{})
+	uncompressed, _ := uncompressedSSTables.NewSSTable(newMemTable("HDD"))
+	_ = uncompressed.Write()
+	_ = uncompressedSSTables.AllowSearchIn(uncompressed)
+
+	compressedSSTables, _ := NewSSTablesWith(directory, nil, SnappyCompression{}, comparator.StringKeyComparator{})
+	compressed, _ := compressedSSTables.NewSSTable(newMemTable("SSD"))
+	_ = compressed.Write()
+	_ = compressedSSTables.AllowSearchIn(compressed)
+
+	if compressed.size >= uncompressed.size {
+		t.Fatalf("Expected the compressed ssTable to be smaller than %v bytes, received %v", uncompressed.size, compressed.size)
+	}
+
+	ssTablesAfterRestart, _ := NewSSTablesWith(directory, nil, NoCompression{}, comparator.StringKeyComparator{})
+	for _, keyPrefix := range []string{"HDD", "SSD"} {
+		for count := 1; count <= 200; count++ {
+			expected := fmt.Sprintf(`{"disk": "%v", "capacity": "%vGB", "type": "solid state drive"}`, keyPrefix, count)
+			getResult := ssTablesAfterRestart.Get(model.NewSlice([]byte(fmt.Sprintf("%v-%03d", keyPrefix, count))), comparator.StringKeyComparator{})
+			if getResult.Value.AsString() != expected {
+				t.Fatalf("Expected %v, received %v", expected, getResult.Value.AsString())
+			}
+		}
+	}
+}
+
+func TestGetsTheVersionsOfKeysAtASequenceFromSSTableSpanningMultipleBlocks(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	for sequence := uint64(1); sequence <= 10; sequence++ {
+		for count := 1; count <= 50; count++ {
+			memTable.PutKeyValuePair(model.KeyValuePair{
+				Key:      model.NewSlice([]byte(fmt.Sprintf("Key-%03d", count))),
+				Value:    model.NewSlice([]byte(fmt.Sprintf("Value-%v-%0100d", sequence, count))),
+				Sequence: sequence,
+			})
+		}
+	}
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+
+	if len(ssTable.blockHandles) <= 1 {
+		t.Fatalf("Expected the ssTable to have multiple blocks, received %v", len(ssTable.blockHandles))
+	}
+	for count := 1; count <= 50; count++ {
+		key := model.NewSlice([]byte(fmt.Sprintf("Key-%03d", count)))
+		for _, sequence := range []uint64{1, 5, 10} {
+			getResult := ssTable.getUnresolved(key, sequence, comparator.StringKeyComparator{})
+			if getResult.Sequence != sequence || getResult.Value.AsString() != fmt.Sprintf("Value-%v-%0100d", sequence, count) {
+				t.Fatalf("Expected %v at sequence %v, received %v at sequence %v", fmt.Sprintf("Value-%v-%0100d", sequence, count), sequence, getResult.Value.AsString(), getResult.Sequence)
+			}
+		}
+		if getResult := ssTable.getUnresolved(key, 0, comparator.StringKeyComparator{}); getResult.Exists {
+			t.Fatalf("Expected key %v to be missing at sequence 0, but was present", key.AsString())
+		}
+	}
+
+	iterator := ssTable.newIteratorAt(5, comparator.StringKeyComparator{})
+	defer iterator.Close()
+
+	count := 1
+	for iterator.Seek(model.NilSlice()); iterator.IsValid(); iterator.Next() {
+		if iterator.Value().AsString() != fmt.Sprintf("Value-5-%0100d", count) {
+			t.Fatalf("Expected %v, received %v", fmt.Sprintf("Value-5-%0100d", count), iterator.Value().AsString())
+		}
+		count = count + 1
+	}
+	if count != 51 {
+		t.Fatalf("Expected the iterator to return %v keys, received %v", 50, count-1)
+	}
+}
+
+func TestMultiGetsKeysConcurrentlyWithResultsAlignedToTheKeys(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	for count := 1; count <= 100; count = count + 2 {
+		memTable.Put(model.NewSlice([]byte(fmt.Sprintf("Key-%03d", count))), model.NewSlice([]byte(fmt.Sprintf("Value-%03d", count))))
+	}
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	_ = ssTables.AllowSearchIn(ssTable)
+
+	var keys []model.Slice
+	for count := 100; count >= 1; count-- {
+		keys = append(keys, model.NewSlice([]byte(fmt.Sprintf("Key-%03d", count))))
+	}
+	multiGetResult := ssTables.MultiGet(keys, comparator.StringKeyComparator{})
+
+	if len(multiGetResult.Values) != len(keys) {
+		t.Fatalf("Expected %v results, received %v", len(keys), len(multiGetResult.Values))
+	}
+	for index, getResult := range multiGetResult.Values {
+		count := 100 - index
+		if getResult.Key.AsString() != keys[index].AsString() {
+			t.Fatalf("Expected result of key %v at position %v, received %v", keys[index].AsString(), index, getResult.Key.AsString())
+		}
+		if exists := count%2 == 1; getResult.Exists != exists {
+			t.Fatalf("Expected key %v to exist %v, received %v", keys[index].AsString(), exists, getResult.Exists)
+		}
+		if getResult.Exists && getResult.Value.AsString() != fmt.Sprintf("Value-%03d", count) {
+			t.Fatalf("Expected %v, received %v", fmt.Sprintf("Value-%03d", count), getResult.Value.AsString())
+		}
+	}
+}
+
+func TestReturnsACorruptionErrorWhileGettingFromATruncatedSSTable(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	_ = ssTables.AllowSearchIn(ssTable)
+
+	_ = os.Truncate(ssTable.store.file.Name(), 4)
+
+	getResult := ssTables.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{})
+	var corruptionError *CorruptionError
+	if !errors.As(getResult.Err, &corruptionError) {
+		t.Fatalf("Expected a CorruptionError while getting from a truncated ssTable, received %v", getResult.Err)
+	}
+	if getResult.Exists || corruptionError.Offset != 0 {
+		t.Fatalf("Expected the key to be missing and the corruption at offset 0, received exists %v and offset %v", getResult.Exists, corruptionError.Offset)
+	}
+}
+
+func TestReturnsAnIOErrorWhileGettingFromAnUnreadableSSTable(t *testing.T) {
+	memTable := memory.NewMemTable(10, comparator.StringKeyComparator{})
+	memTable.Put(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	ssTables, _ := NewSSTables(directory)
+	ssTable, _ := ssTables.NewSSTable(memTable)
+	_ = ssTable.Write()
+	_ = ssTables.AllowSearchIn(ssTable)
+
+	ssTable.Close()
+
+	getResult := ssTables.Get(model.NewSlice([]byte("HDD")), comparator.StringKeyComparator{})
+	var ioError *IOError
+	if !errors.As(getResult.Err, &ioError) {
+		t.Fatalf("Expected an IOError while getting from a closed ssTable, received %v", getResult.Err)
+	}
+	if !errors.Is(getResult.Err, os.ErrClosed) {
+		t.Fatalf("Expected the IOError to wrap %v, received %v", os.ErrClosed, ioError.Err)
+	}
+}
@@ -51,3 +51,16 @@ func (store *Store) Size() (int64, error) {
 func (store *Store) Sync() error {
 	return store.file.Sync()
 }
+
+func (store *Store) Close() error {
+	return store.file.Close()
+}
+
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
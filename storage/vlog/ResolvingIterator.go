@@ -0,0 +1,86 @@
+package vlog
+
+import (
+	"storage-engine-workshop/db/model"
+	"storage-engine-workshop/storage/iterator"
+)
+
+// ResolvingIterator reads the values which are kept in the value log, in place of their pointers.
+// The value at a position is read as the iterator is positioned, the iterator becomes invalid if the value can not be read
+type ResolvingIterator struct {
+	iterator iterator.Iterator
+	valueLog *ValueLog
+	value    model.Slice
+	err      error
+}
+
+func NewResolvingIterator(iterator iterator.Iterator, valueLog *ValueLog) *ResolvingIterator {
+	return &ResolvingIterator{
+		iterator: iterator,
+		valueLog: valueLog,
+	}
+}
+
+func (resolvingIterator *ResolvingIterator) Seek(key model.Slice) {
+	resolvingIterator.iterator.Seek(key)
+	resolvingIterator.resolve()
+}
+
+func (resolvingIterator *ResolvingIterator) Next() {
+	resolvingIterator.iterator.Next()
+	resolvingIterator.resolve()
+}
+
+func (resolvingIterator *ResolvingIterator) IsValid() bool {
+	return resolvingIterator.err == nil && resolvingIterator.iterator.IsValid()
+}
+
+func (resolvingIterator *ResolvingIterator) Key() model.Slice {
+	return resolvingIterator.iterator.Key()
+}
+
+func (resolvingIterator *ResolvingIterator) Value() model.Slice {
+	return resolvingIterator.value
+}
+
+func (resolvingIterator *ResolvingIterator) IsDeleted() bool {
+	return resolvingIterator.iterator.IsDeleted()
+}
+
+func (resolvingIterator *ResolvingIterator) IsValuePointer() bool {
+	return false
+}
+
+func (resolvingIterator *ResolvingIterator) Sequence() uint64 {
+	return resolvingIterator.iterator.Sequence()
+}
+
+// Err returns the error of the underlying iterator, or else the error of the value which could not be read from the value log
+func (resolvingIterator *ResolvingIterator) Err() error {
+	if err := resolvingIterator.iterator.Err(); err != nil {
+		return err
+	}
+	return resolvingIterator.err
+}
+
+func (resolvingIterator *ResolvingIterator) Close() {
+	resolvingIterator.iterator.Close()
+}
+
+// resolve reads the value at the current position, a value which can not be read stops the iteration
+func (resolvingIterator *ResolvingIterator) resolve() {
+	resolvingIterator.value = model.NilSlice()
+	if resolvingIterator.err != nil || !resolvingIterator.iterator.IsValid() {
+		return
+	}
+	if !resolvingIterator.iterator.IsValuePointer() {
+		resolvingIterator.value = resolvingIterator.iterator.Value()
+		return
+	}
+	value, err := resolvingIterator.valueLog.Resolve(resolvingIterator.iterator.Value())
+	if err != nil {
+		resolvingIterator.err = err
+		return
+	}
+	resolvingIterator.value = value
+}
@@ -0,0 +1,321 @@
+package vlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"storage-engine-workshop/db/model"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	subDirectoryName              = "vlog"
+	subDirectoryPermission        = 0744
+	fileExtension                 = ".vlog"
+	recordHeaderSize              = 8
+	recordChecksumSize            = 4
+	DefaultMaxFileSizeBytes int64 = 64 * 1024 * 1024
+)
+
+var (
+	bigEndian  = binary.BigEndian
+	crc32Table = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// ValueLog stores the values which are too large to be copied through the memTable, WAL and ssTables.
+// The memTable and ssTables keep a ValuePointer to the record instead.
+// Layout of a record: 4 bytes key size | 4 bytes value size | key | value | 4 bytes crc32 of the preceding bytes.
+// A new file is started on every open and once the active file reaches its max size, older files are only read
+// or garbage collected. Append and CollectGarbage must be called from a single goroutine, Read can be called from any goroutine.
+// A collected file is retired at the sequence of the rewrite of its live values, it remains readable until no reader older than that sequence is alive.
+type ValueLog struct {
+	directory        string
+	maxFileSizeBytes int64
+	files            map[uint32]*os.File
+	retiredFiles     map[uint32]uint64
+	activeFile       *os.File
+	activeFileId     uint32
+	activeFileSize   int64
+	nextFileId       uint32
+	lock             sync.RWMutex
+}
+
+func NewValueLog(directory string, maxFileSizeBytes int64) (*ValueLog, error) {
+	if len(directory) == 0 {
+		return nil, errors.New("directory can not be empty while creating value log")
+	}
+	subDirectory := path.Join(directory, subDirectoryName)
+	if _, err := os.Stat(subDirectory); os.IsNotExist(err) {
+		if err := os.Mkdir(subDirectory, subDirectoryPermission); err != nil {
+			return nil, err
+		}
+	}
+	valueLog := &ValueLog{
+		directory:        subDirectory,
+		maxFileSizeBytes: maxFileSizeBytes,
+		files:            make(map[uint32]*os.File),
+		retiredFiles:     make(map[uint32]uint64),
+		nextFileId:       1,
+	}
+	if err := valueLog.init(); err != nil {
+		return nil, err
+	}
+	return valueLog, nil
+}
+
+func (valueLog *ValueLog) Append(key, value model.Slice) (ValuePointer, error) {
+	valueLog.lock.Lock()
+	defer valueLog.lock.Unlock()
+
+	if valueLog.activeFile == nil || valueLog.activeFileSize >= valueLog.maxFileSizeBytes {
+		if err := valueLog.rollOverActiveFile(); err != nil {
+			return ValuePointer{}, err
+		}
+	}
+	record := marshal(key, value)
+	if _, err := valueLog.activeFile.Write(record); err != nil {
+		return ValuePointer{}, err
+	}
+	valuePointer := ValuePointer{FileId: valueLog.activeFileId, Offset: valueLog.activeFileSize, Length: uint32(len(record))}
+	valueLog.activeFileSize = valueLog.activeFileSize + int64(len(record))
+	return valuePointer, nil
+}
+
+func (valueLog *ValueLog) Sync() error {
+	valueLog.lock.RLock()
+	defer valueLog.lock.RUnlock()
+
+	if valueLog.activeFile == nil {
+		return nil
+	}
+	return valueLog.activeFile.Sync()
+}
+
+// Resolve reads the value which the encoded value pointer points to
+func (valueLog *ValueLog) Resolve(encodedValuePointer model.Slice) (model.Slice, error) {
+	valuePointer, err := DecodeValuePointer(encodedValuePointer)
+	if err != nil {
+		return model.NilSlice(), err
+	}
+	return valueLog.Read(valuePointer)
+}
+
+// Read holds the lock while reading, so that the file is not closed by garbage collection in between
+func (valueLog *ValueLog) Read(valuePointer ValuePointer) (model.Slice, error) {
+	valueLog.lock.RLock()
+	defer valueLog.lock.RUnlock()
+
+	file, ok := valueLog.files[valuePointer.FileId]
+	if !ok {
+		return model.NilSlice(), errors.New(fmt.Sprintf("value log file with id %v does not exist", valuePointer.FileId))
+	}
+	record := make([]byte, valuePointer.Length)
+	if _, err := file.ReadAt(record, valuePointer.Offset); err != nil {
+		return model.NilSlice(), err
+	}
+	_, value, ok := unmarshal(record)
+	if !ok {
+		return model.NilSlice(), errors.New(fmt.Sprintf("corrupt value log record in file %v at offset %v", file.Name(), valuePointer.Offset))
+	}
+	return value, nil
+}
+
+// CollectGarbage scans the oldest value log file which is neither active nor retired. isLive decides if a record is still referenced,
+// the live records are handed over to rewrite, which must durably write them again and return the sequence from which readers see them.
+// The file is retired at that sequence, older readers and snapshots may still read its records through older versions of the keys.
+// The retired files are deleted once oldestReadSequence, the sequence of the oldest live reader, is not older than their retirement.
+// Returns false if there is no file to collect.
+func (valueLog *ValueLog) CollectGarbage(
+	isLive func(key model.Slice, valuePointer ValuePointer) bool,
+	rewrite func(keyValuePairs []model.KeyValuePair) (uint64, error),
+	oldestReadSequence func() uint64,
+) (bool, error) {
+	oldestFileId := func() (uint32, bool) {
+		valueLog.lock.RLock()
+		defer valueLog.lock.RUnlock()
+
+		var fileIds []uint32
+		for fileId := range valueLog.files {
+			if _, retired := valueLog.retiredFiles[fileId]; retired {
+				continue
+			}
+			if valueLog.activeFile == nil || fileId != valueLog.activeFileId {
+				fileIds = append(fileIds, fileId)
+			}
+		}
+		if len(fileIds) == 0 {
+			return 0, false
+		}
+		sort.Slice(fileIds, func(i, j int) bool {
+			return fileIds[i] < fileIds[j]
+		})
+		return fileIds[0], true
+	}
+	liveKeyValuePairs := func(fileId uint32) ([]model.KeyValuePair, error) {
+		contents, err := ioutil.ReadFile(valueLog.filePath(fileId))
+		if err != nil {
+			return nil, err
+		}
+		var keyValuePairs []model.KeyValuePair
+		//a partially written record can only be the last one in a file, the scan stops at it
+		for offset := 0; offset+recordHeaderSize+recordChecksumSize <= len(contents); {
+			length := recordHeaderSize + int(bigEndian.Uint32(contents[offset:])) + int(bigEndian.Uint32(contents[offset+4:])) + recordChecksumSize
+			if offset+length > len(contents) {
+				break
+			}
+			key, value, ok := unmarshal(contents[offset : offset+length])
+			if !ok {
+				break
+			}
+			if isLive(key, ValuePointer{FileId: fileId, Offset: int64(offset), Length: uint32(length)}) {
+				keyValuePairs = append(keyValuePairs, model.KeyValuePair{Key: key, Value: value})
+			}
+			offset = offset + length
+		}
+		return keyValuePairs, nil
+	}
+	retire := func(fileId uint32, sequence uint64) {
+		valueLog.lock.Lock()
+		defer valueLog.lock.Unlock()
+
+		valueLog.retiredFiles[fileId] = sequence
+	}
+	//a reader older than the retirement may resolve a value pointer into the file, the file is closed only once no such reader is alive
+	removeRetiredFiles := func() error {
+		oldestSequence := oldestReadSequence()
+
+		valueLog.lock.Lock()
+		var files []*os.File
+		for fileId, sequence := range valueLog.retiredFiles {
+			if sequence <= oldestSequence {
+				files = append(files, valueLog.files[fileId])
+				delete(valueLog.files, fileId)
+				delete(valueLog.retiredFiles, fileId)
+			}
+		}
+		valueLog.lock.Unlock()
+
+		for _, file := range files {
+			_ = file.Close()
+			if err := os.Remove(file.Name()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	fileId, ok := oldestFileId()
+	if !ok {
+		return false, removeRetiredFiles()
+	}
+	keyValuePairs, err := liveKeyValuePairs(fileId)
+	if err != nil {
+		return false, err
+	}
+	sequence, err := rewrite(keyValuePairs)
+	if err != nil {
+		return false, err
+	}
+	retire(fileId, sequence)
+	return true, removeRetiredFiles()
+}
+
+// Close closes all the value log files and returns the first error
+func (valueLog *ValueLog) Close() error {
+	valueLog.lock.Lock()
+	defer valueLog.lock.Unlock()
+
+	var err error
+	for _, file := range valueLog.files {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// rollOverActiveFile syncs the active file before starting a new one, so that a sync of the value log
+// only needs to sync the active file
+func (valueLog *ValueLog) rollOverActiveFile() error {
+	if valueLog.activeFile != nil {
+		if err := valueLog.activeFile.Sync(); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(valueLog.filePath(valueLog.nextFileId), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	valueLog.files[valueLog.nextFileId] = file
+	valueLog.activeFile, valueLog.activeFileId, valueLog.activeFileSize = file, valueLog.nextFileId, 0
+	valueLog.nextFileId = valueLog.nextFileId + 1
+	return nil
+}
+
+func (valueLog *ValueLog) filePath(fileId uint32) string {
+	return path.Join(valueLog.directory, strconv.Itoa(int(fileId))+fileExtension)
+}
+
+func (valueLog *ValueLog) init() error {
+	files, err := ioutil.ReadDir(valueLog.directory)
+	if err != nil {
+		return err
+	}
+	for _, fileInfo := range files {
+		if path.Ext(fileInfo.Name()) != fileExtension {
+			continue
+		}
+		fileId, err := strconv.Atoi(strings.TrimSuffix(fileInfo.Name(), fileExtension))
+		if err != nil {
+			continue
+		}
+		file, err := os.Open(path.Join(valueLog.directory, fileInfo.Name()))
+		if err != nil {
+			return err
+		}
+		valueLog.files[uint32(fileId)] = file
+		if uint32(fileId) >= valueLog.nextFileId {
+			valueLog.nextFileId = uint32(fileId) + 1
+		}
+	}
+	return nil
+}
+
+func marshal(key, value model.Slice) []byte {
+	keySize, valueSize := key.Size(), value.Size()
+	bytes := make([]byte, recordHeaderSize+keySize+valueSize+recordChecksumSize)
+
+	bigEndian.PutUint32(bytes, uint32(keySize))
+	bigEndian.PutUint32(bytes[4:], uint32(valueSize))
+	copy(bytes[recordHeaderSize:], key.GetRawContent())
+	copy(bytes[recordHeaderSize+keySize:], value.GetRawContent())
+
+	checksumOffset := len(bytes) - recordChecksumSize
+	bigEndian.PutUint32(bytes[checksumOffset:], crc32.Checksum(bytes[:checksumOffset], crc32Table))
+	return bytes
+}
+
+// unmarshal returns the key and the value of a record, and false if the record is malformed or its checksum does not match
+func unmarshal(record []byte) (model.Slice, model.Slice, bool) {
+	if len(record) < recordHeaderSize+recordChecksumSize {
+		return model.NilSlice(), model.NilSlice(), false
+	}
+	keySize, valueSize := int(bigEndian.Uint32(record)), int(bigEndian.Uint32(record[4:]))
+	checksumOffset := recordHeaderSize + keySize + valueSize
+	if checksumOffset+recordChecksumSize != len(record) {
+		return model.NilSlice(), model.NilSlice(), false
+	}
+	if crc32.Checksum(record[:checksumOffset], crc32Table) != bigEndian.Uint32(record[checksumOffset:]) {
+		return model.NilSlice(), model.NilSlice(), false
+	}
+	key := record[recordHeaderSize : recordHeaderSize+keySize]
+	value := record[recordHeaderSize+keySize : checksumOffset]
+	return model.NewSlice(key), model.NewSlice(value), true
+}
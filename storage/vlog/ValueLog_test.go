@@ -0,0 +1,181 @@
+package vlog
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"storage-engine-workshop/db/model"
+	"testing"
+)
+
+func tempDirectory() string {
+	dir, err := ioutil.TempDir(".", "vlog")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return dir
+}
+
+func TestAppendsAValueAndReadsItByItsPointer(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	valueLog, _ := NewValueLog(directory, DefaultMaxFileSizeBytes)
+	defer valueLog.Close()
+
+	_, _ = valueLog.Append(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	valuePointer, _ := valueLog.Append(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+
+	value, err := valueLog.Resolve(valuePointer.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error while resolving the value pointer, received %v", err)
+	}
+	if value.AsString() != "Solid state drive" {
+		t.Fatalf("Expected %v, received %v", "Solid state drive", value.AsString())
+	}
+}
+
+func TestReadsAValueAfterReopeningTheValueLogSimulatingARestart(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	valueLog, _ := NewValueLog(directory, DefaultMaxFileSizeBytes)
+	valuePointer, _ := valueLog.Append(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	_ = valueLog.Sync()
+	valueLog.Close()
+
+	reopened, _ := NewValueLog(directory, DefaultMaxFileSizeBytes)
+	defer reopened.Close()
+
+	newPointer, _ := reopened.Append(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+	if newPointer.FileId == valuePointer.FileId {
+		t.Fatalf("Expected a new file to be started after reopening, received file id %v", newPointer.FileId)
+	}
+	value, _ := reopened.Read(valuePointer)
+	if value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v, received %v", "Hard disk", value.AsString())
+	}
+}
+
+func TestFailsToReadACorruptRecord(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	valueLog, _ := NewValueLog(directory, DefaultMaxFileSizeBytes)
+	defer valueLog.Close()
+
+	valuePointer, _ := valueLog.Append(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	file, _ := os.OpenFile(path.Join(directory, subDirectoryName, "1"+fileExtension), os.O_RDWR, 0644)
+	_, _ = file.WriteAt([]byte("X"), recordHeaderSize+3)
+	_ = file.Close()
+
+	if _, err := valueLog.Read(valuePointer); err == nil {
+		t.Fatalf("Expected an error while reading a corrupt record")
+	}
+}
+
+func TestRollsOverTheActiveFileOnceItReachesItsMaxSize(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	valueLog, _ := NewValueLog(directory, 16)
+	defer valueLog.Close()
+
+	first, _ := valueLog.Append(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	second, _ := valueLog.Append(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+
+	if first.FileId == second.FileId {
+		t.Fatalf("Expected the values to be in different files, received file id %v for both", first.FileId)
+	}
+}
+
+func TestCollectsGarbageByRewritingOnlyTheLiveValues(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	valueLog, _ := NewValueLog(directory, 16)
+	defer valueLog.Close()
+
+	live, _ := valueLog.Append(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	_, _ = valueLog.Append(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+
+	var rewritten []model.KeyValuePair
+	isLive := func(key model.Slice, valuePointer ValuePointer) bool {
+		return valuePointer == live
+	}
+	rewrite := func(keyValuePairs []model.KeyValuePair) (uint64, error) {
+		rewritten = keyValuePairs
+		return 1, nil
+	}
+	collected, err := valueLog.CollectGarbage(isLive, rewrite, oldestReadSequenceOf(model.LatestSequence))
+	if err != nil || !collected {
+		t.Fatalf("Expected the oldest file to be collected, received collected %v and error %v", collected, err)
+	}
+	if len(rewritten) != 1 || rewritten[0].Key.AsString() != "HDD" || rewritten[0].Value.AsString() != "Hard disk" {
+		t.Fatalf("Expected only the live value of key %v to be rewritten, received %v", "HDD", rewritten)
+	}
+	if _, err := os.Stat(path.Join(directory, subDirectoryName, "1"+fileExtension)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the collected file to be deleted")
+	}
+	if _, err := valueLog.Read(live); err == nil {
+		t.Fatalf("Expected an error while reading a value from a collected file")
+	}
+}
+
+func TestDoesNotCollectTheActiveFile(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	valueLog, _ := NewValueLog(directory, DefaultMaxFileSizeBytes)
+	defer valueLog.Close()
+
+	_, _ = valueLog.Append(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	collected, _ := valueLog.CollectGarbage(
+		func(key model.Slice, valuePointer ValuePointer) bool { return false },
+		func(keyValuePairs []model.KeyValuePair) (uint64, error) { return 1, nil },
+		oldestReadSequenceOf(model.LatestSequence),
+	)
+	if collected {
+		t.Fatalf("Expected the active file not to be collected")
+	}
+}
+
+func TestKeepsACollectedFileReadableUntilNoOlderReaderIsAlive(t *testing.T) {
+	directory := tempDirectory()
+	defer os.RemoveAll(directory)
+
+	valueLog, _ := NewValueLog(directory, 16)
+	defer valueLog.Close()
+
+	older, _ := valueLog.Append(model.NewSlice([]byte("HDD")), model.NewSlice([]byte("Hard disk")))
+	_, _ = valueLog.Append(model.NewSlice([]byte("SDD")), model.NewSlice([]byte("Solid state drive")))
+
+	isLive := func(key model.Slice, valuePointer ValuePointer) bool {
+		return false
+	}
+	rewrite := func(keyValuePairs []model.KeyValuePair) (uint64, error) {
+		return 5, nil
+	}
+	collected, err := valueLog.CollectGarbage(isLive, rewrite, oldestReadSequenceOf(4))
+	if err != nil || !collected {
+		t.Fatalf("Expected the oldest file to be collected, received collected %v and error %v", collected, err)
+	}
+	if value, err := valueLog.Read(older); err != nil || value.AsString() != "Hard disk" {
+		t.Fatalf("Expected %v to be readable by a reader older than the collection, received %v and error %v", "Hard disk", value.AsString(), err)
+	}
+
+	collected, err = valueLog.CollectGarbage(isLive, rewrite, oldestReadSequenceOf(5))
+	if err != nil || collected {
+		t.Fatalf("Expected no file to be collected, received collected %v and error %v", collected, err)
+	}
+	if _, err := os.Stat(path.Join(directory, subDirectoryName, "1"+fileExtension)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the collected file to be deleted once no older reader is alive")
+	}
+}
+
+func oldestReadSequenceOf(sequence uint64) func() uint64 {
+	return func() uint64 {
+		return sequence
+	}
+}
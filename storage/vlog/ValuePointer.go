@@ -0,0 +1,37 @@
+package vlog
+
+import (
+	"errors"
+	"fmt"
+	"storage-engine-workshop/db/model"
+)
+
+const valuePointerSize = 16
+
+// ValuePointer locates a record in the value log, it is stored in the memTable and ssTables instead of a large value.
+// Encoding: 4 bytes file id | 8 bytes offset | 4 bytes length of the record
+type ValuePointer struct {
+	FileId uint32
+	Offset int64
+	Length uint32
+}
+
+func (valuePointer ValuePointer) Encode() model.Slice {
+	bytes := make([]byte, valuePointerSize)
+	bigEndian.PutUint32(bytes, valuePointer.FileId)
+	bigEndian.PutUint64(bytes[4:], uint64(valuePointer.Offset))
+	bigEndian.PutUint32(bytes[12:], valuePointer.Length)
+	return model.NewSlice(bytes)
+}
+
+func DecodeValuePointer(slice model.Slice) (ValuePointer, error) {
+	bytes := slice.GetRawContent()
+	if len(bytes) != valuePointerSize {
+		return ValuePointer{}, errors.New(fmt.Sprintf("value pointer must be %v bytes, received %v bytes", valuePointerSize, len(bytes)))
+	}
+	return ValuePointer{
+		FileId: bigEndian.Uint32(bytes),
+		Offset: int64(bigEndian.Uint64(bytes[4:])),
+		Length: bigEndian.Uint32(bytes[12:]),
+	}, nil
+}